@@ -0,0 +1,110 @@
+package stdsdk
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+)
+
+// RequestError identifies the call that failed, Path is the template
+// passed to the client rather than the expanded url path
+type RequestError struct {
+	Err       error
+	Method    string
+	Path      string
+	RequestID string
+	Status    int
+}
+
+func (e *RequestError) Error() string {
+	s := fmt.Sprintf("%s %s", e.Method, e.Path)
+
+	if e.Status > 0 {
+		s += fmt.Sprintf(" %d", e.Status)
+	}
+
+	if e.RequestID != "" {
+		s += fmt.Sprintf(" (request %s)", e.RequestID)
+	}
+
+	return fmt.Sprintf("%s: %s", s, e.Err)
+}
+
+func (e *RequestError) Unwrap() error {
+	return e.Err
+}
+
+type templateKey struct{}
+
+type outcomeKey struct{}
+
+// outcome is filled in by do so errors raised after the response was read
+// can still report its status
+type outcome struct {
+	requestID string
+	status    int
+}
+
+func withOutcome(req *http.Request) (*http.Request, *outcome) {
+	if o, ok := req.Context().Value(outcomeKey{}).(*outcome); ok {
+		return req, o
+	}
+
+	o := &outcome{}
+
+	return req.WithContext(context.WithValue(req.Context(), outcomeKey{}, o)), o
+}
+
+func observeOutcome(req *http.Request, res *http.Response) {
+	if o, ok := req.Context().Value(outcomeKey{}).(*outcome); ok {
+		o.requestID = requestID(req, res)
+		o.status = res.StatusCode
+	}
+}
+
+func requestID(req *http.Request, res *http.Response) string {
+	for _, h := range []string{"X-Request-Id", "X-Amzn-Requestid", "X-Correlation-Id"} {
+		if res != nil {
+			if id := res.Header.Get(h); id != "" {
+				return id
+			}
+		}
+		if id := req.Header.Get(h); id != "" {
+			return id
+		}
+	}
+
+	return ""
+}
+
+func annotate(req *http.Request, o *outcome, err error) error {
+	var re *RequestError
+
+	if errors.As(err, &re) {
+		return err
+	}
+
+	path := req.URL.Path
+
+	if t, ok := req.Context().Value(templateKey{}).(string); ok {
+		path = t
+	}
+
+	e := &RequestError{Err: err, Method: req.Method, Path: path}
+
+	if o != nil {
+		e.RequestID = o.requestID
+		e.Status = o.status
+	}
+
+	return e
+}
+
+func annotateResponse(res *http.Response, err error) error {
+	if res.Request == nil {
+		return err
+	}
+
+	return annotate(res.Request, &outcome{requestID: requestID(res.Request, res), status: res.StatusCode}, err)
+}
@@ -0,0 +1,48 @@
+package stdsdk
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+)
+
+type APIKeyPlacement int
+
+const (
+	APIKeyHeader APIKeyPlacement = iota
+	APIKeyBearer
+	APIKeyQuery
+)
+
+type APIKey struct {
+	Key       string
+	Name      string
+	Placement APIKeyPlacement
+}
+
+func (k APIKey) credentials() []string {
+	return []string{k.Key, url.QueryEscape(k.Key)}
+}
+
+func (k APIKey) Apply(ctx context.Context, req *http.Request) error {
+	switch k.Placement {
+	case APIKeyBearer:
+		req.Header.Set("Authorization", "Bearer "+k.Key)
+	case APIKeyQuery:
+		name := k.Name
+		if name == "" {
+			name = "api_key"
+		}
+		q := req.URL.Query()
+		q.Set(name, k.Key)
+		req.URL.RawQuery = q.Encode()
+	default:
+		name := k.Name
+		if name == "" {
+			name = "X-API-Key"
+		}
+		req.Header.Set(name, k.Key)
+	}
+
+	return nil
+}
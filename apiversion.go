@@ -0,0 +1,126 @@
+package stdsdk
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+type APIVersion struct {
+	Header          string
+	OnWarning       func(err *VersionError)
+	PathPrefix      bool
+	SupportedHeader string
+	Version         string
+}
+
+type VersionError struct {
+	Deprecated  bool
+	Sunset      time.Time
+	Supported   []string
+	Unsupported bool
+	Version     string
+}
+
+func (e *VersionError) Error() string {
+	if e.Unsupported {
+		return fmt.Sprintf("api version %s is not supported by the server (supported: %s)", e.Version, strings.Join(e.Supported, ", "))
+	}
+
+	if !e.Sunset.IsZero() {
+		return fmt.Sprintf("api version %s is deprecated and will be removed on %s", e.Version, e.Sunset.Format("2006-01-02"))
+	}
+
+	return fmt.Sprintf("api version %s is deprecated", e.Version)
+}
+
+func (v *APIVersion) header() string {
+	if v.Header == "" {
+		return "X-Api-Version"
+	}
+
+	return v.Header
+}
+
+func (v *APIVersion) supportedHeader() string {
+	if v.SupportedHeader == "" {
+		return "X-Api-Supported-Versions"
+	}
+
+	return v.SupportedHeader
+}
+
+// versionPath joins path to the endpoint, the version prefix goes between the
+// endpoint and any Sub prefixes
+func (c *Client) versionPath(path string) string {
+	base := rawPath(c.Endpoint)
+
+	if c.APIVersion == nil || !c.APIVersion.PathPrefix || c.APIVersion.Version == "" {
+		return joinPath(base, path)
+	}
+
+	root := joinPath(strings.TrimSuffix(base, c.sub), "/"+url.PathEscape(c.APIVersion.Version))
+
+	return joinPath(root, joinPath(c.sub, path))
+}
+
+// checkVersion fails when the server advertises versions that exclude the
+// pinned one and reports deprecation through OnWarning. A successful unsafe
+// request has already taken effect so it only warns
+func (c *Client) checkVersion(req *http.Request, res *http.Response) error {
+	v := c.APIVersion
+
+	if v == nil || v.Version == "" {
+		return nil
+	}
+
+	e := &VersionError{Supported: headerList(res.Header, v.supportedHeader()), Version: v.Version}
+
+	if len(e.Supported) > 0 {
+		e.Unsupported = true
+
+		for _, s := range e.Supported {
+			if s == v.Version {
+				e.Unsupported = false
+				break
+			}
+		}
+	}
+
+	if e.Unsupported {
+		if res.StatusCode < 300 && !safe(req) {
+			if v.OnWarning != nil {
+				v.OnWarning(e)
+			}
+			return nil
+		}
+		return e
+	}
+
+	if d := res.Header.Get("Deprecation"); d != "" && d != "false" {
+		e.Deprecated = true
+	}
+
+	if s, err := http.ParseTime(res.Header.Get("Sunset")); err == nil {
+		e.Deprecated = true
+		e.Sunset = s
+	}
+
+	if e.Deprecated && v.OnWarning != nil {
+		v.OnWarning(e)
+	}
+
+	return nil
+}
+
+// safe reports whether req cannot have changed anything on the server
+func safe(req *http.Request) bool {
+	switch req.Method {
+	case "GET", "HEAD", "OPTIONS":
+		return true
+	}
+
+	return false
+}
@@ -0,0 +1,65 @@
+package stdsdk_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/liamdawson/stdsdk"
+)
+
+func TestVersionPathSub(t *testing.T) {
+	c, err := stdsdk.New("https://api.example.com/base")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	c.APIVersion = &stdsdk.APIVersion{PathPrefix: true, Version: "v2"}
+
+	u, err := c.Sub("/tenants/t1").Sub("apps").URL("/a1", stdsdk.RequestOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if want := "/base/v2/tenants/t1/apps/a1"; u.Path != want {
+		t.Errorf("path = %s, want %s", u.Path, want)
+	}
+}
+
+func TestVersionUnsupportedAfterWrite(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-Api-Supported-Versions", "v3")
+	}))
+	defer ts.Close()
+
+	c, err := stdsdk.New(ts.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var warned *stdsdk.VersionError
+
+	c.APIVersion = &stdsdk.APIVersion{Version: "v2", OnWarning: func(err *stdsdk.VersionError) { warned = err }}
+
+	writes := map[string]func() error{
+		"DELETE": func() error { return c.Delete("/apps/a", stdsdk.RequestOptions{}, nil) },
+		"POST":   func() error { return c.Post("/apps", stdsdk.RequestOptions{}, nil) },
+		"PUT":    func() error { return c.Put("/apps/a", stdsdk.RequestOptions{}, nil) },
+	}
+
+	for method, write := range writes {
+		warned = nil
+
+		if err := write(); err != nil {
+			t.Errorf("%s: %v", method, err)
+		}
+
+		if warned == nil || !warned.Unsupported {
+			t.Errorf("%s: warning = %v, want unsupported version", method, warned)
+		}
+	}
+
+	if err := c.Get("/apps", stdsdk.RequestOptions{}, nil); err == nil {
+		t.Error("get: expected unsupported version error")
+	}
+}
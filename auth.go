@@ -0,0 +1,120 @@
+package stdsdk
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"net/http"
+)
+
+type Auth interface {
+	Apply(ctx context.Context, req *http.Request) error
+}
+
+// UnauthorizedHandler is implemented by an Auth that can recover from a 401,
+// returning true if the request should be retried
+type UnauthorizedHandler interface {
+	OnUnauthorized(ctx context.Context, res *http.Response) (bool, error)
+}
+
+// credentialed is implemented by auths whose secrets can end up in urls or
+// error messages, they are masked in errors the client returns
+type credentialed interface {
+	credentials() []string
+}
+
+type AuthFunc func(ctx context.Context, req *http.Request) error
+
+func (fn AuthFunc) Apply(ctx context.Context, req *http.Request) error {
+	return fn(ctx, req)
+}
+
+type BasicAuth struct {
+	Username string
+	Password string
+}
+
+func (a BasicAuth) Apply(ctx context.Context, req *http.Request) error {
+	req.Header.Set("Authorization", a.header())
+	return nil
+}
+
+func (a BasicAuth) credentials() []string {
+	return []string{a.Password, a.header()[len("Basic "):]}
+}
+
+func (a BasicAuth) header() string {
+	return "Basic " + base64.StdEncoding.EncodeToString([]byte(a.Username+":"+a.Password))
+}
+
+type TokenAuth struct {
+	Source TokenSource
+}
+
+func (a TokenAuth) Apply(ctx context.Context, req *http.Request) error {
+	t, err := a.Source.Token(ctx)
+	if err != nil {
+		return err
+	}
+
+	if t == nil {
+		return fmt.Errorf("token source returned no token")
+	}
+
+	req.Header.Set("Authorization", "Bearer "+t.Value)
+
+	return nil
+}
+
+func (a TokenAuth) OnUnauthorized(ctx context.Context, res *http.Response) (bool, error) {
+	if cs, ok := a.Source.(*CachedTokenSource); ok {
+		cs.Invalidate()
+		return true, nil
+	}
+
+	return false, nil
+}
+
+type multiAuth []Auth
+
+func MultiAuth(auths ...Auth) Auth {
+	return multiAuth(auths)
+}
+
+func (m multiAuth) Apply(ctx context.Context, req *http.Request) error {
+	for _, a := range m {
+		if err := a.Apply(ctx, req); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (m multiAuth) credentials() []string {
+	s := []string{}
+
+	for _, a := range m {
+		if cr, ok := a.(credentialed); ok {
+			s = append(s, cr.credentials()...)
+		}
+	}
+
+	return s
+}
+
+func (m multiAuth) OnUnauthorized(ctx context.Context, res *http.Response) (bool, error) {
+	retry := false
+
+	for _, a := range m {
+		if uh, ok := a.(UnauthorizedHandler); ok {
+			r, err := uh.OnUnauthorized(ctx, res)
+			if err != nil {
+				return false, err
+			}
+			retry = retry || r
+		}
+	}
+
+	return retry, nil
+}
@@ -0,0 +1,208 @@
+package stdsdk
+
+import (
+	"io"
+	"sync"
+)
+
+type BufferPolicy int
+
+const (
+	BufferBlock BufferPolicy = iota
+	BufferDropOldest
+)
+
+// StreamBuffer decouples a slow reader from the connection, Block applies
+// backpressure once Size bytes are buffered while DropOldest discards the
+// oldest data to make room
+type StreamBuffer struct {
+	Policy BufferPolicy
+	Size   int
+}
+
+type BufferStats struct {
+	Buffered  int64
+	Dropped   int64
+	HighWater int64
+	Received  int64
+}
+
+// BufferedReader is returned in place of the raw stream when a request sets
+// a StreamBuffer
+type BufferedReader struct {
+	closer io.Closer
+	policy BufferPolicy
+	size   int
+
+	lock   sync.Mutex
+	cond   *sync.Cond
+	chunks [][]byte
+	closed bool
+	err    error
+	stats  BufferStats
+}
+
+func newBufferedReader(b *StreamBuffer, closer io.Closer) *BufferedReader {
+	r := &BufferedReader{closer: closer, policy: b.Policy, size: b.Size}
+
+	if r.size <= 0 {
+		r.size = 1024 * 1024
+	}
+
+	r.cond = sync.NewCond(&r.lock)
+
+	return r
+}
+
+// bufferStream starts filling a buffer from rc in the background
+func bufferStream(b *StreamBuffer, rc io.ReadCloser) *BufferedReader {
+	r := newBufferedReader(b, rc)
+
+	go func() {
+		_, err := copyBuffered(r, rc)
+		r.CloseWithError(err)
+	}()
+
+	return r
+}
+
+func (r *BufferedReader) Stats() BufferStats {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	return r.stats
+}
+
+func (r *BufferedReader) Write(p []byte) (int, error) {
+	n := len(p)
+
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	r.stats.Received += int64(n)
+
+	switch r.policy {
+	case BufferDropOldest:
+		if len(p) > r.size {
+			r.stats.Dropped += int64(len(p) - r.size)
+			p = p[len(p)-r.size:]
+		}
+
+		for len(r.chunks) > 0 && int(r.stats.Buffered)+len(p) > r.size {
+			r.stats.Dropped += int64(len(r.chunks[0]))
+			r.stats.Buffered -= int64(len(r.chunks[0]))
+			r.chunks = r.chunks[1:]
+		}
+	default:
+		// oversized writes are queued in pieces as space frees up
+		for len(p) > r.size {
+			if err := r.push(p[:r.size]); err != nil {
+				return 0, err
+			}
+			p = p[r.size:]
+		}
+	}
+
+	if err := r.push(p); err != nil {
+		return 0, err
+	}
+
+	return n, nil
+}
+
+func (r *BufferedReader) push(p []byte) error {
+	for r.policy == BufferBlock && !r.closed && int(r.stats.Buffered)+len(p) > r.size {
+		r.cond.Wait()
+	}
+
+	if r.closed {
+		return io.ErrClosedPipe
+	}
+
+	r.chunks = append(r.chunks, append([]byte(nil), p...))
+	r.stats.Buffered += int64(len(p))
+
+	if r.stats.Buffered > r.stats.HighWater {
+		r.stats.HighWater = r.stats.Buffered
+	}
+
+	r.cond.Broadcast()
+
+	return nil
+}
+
+func (r *BufferedReader) Read(p []byte) (int, error) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	for len(r.chunks) == 0 {
+		if r.err != nil {
+			return 0, r.err
+		}
+		if r.closed {
+			return 0, io.ErrClosedPipe
+		}
+		r.cond.Wait()
+	}
+
+	n := copy(p, r.chunks[0])
+
+	if n == len(r.chunks[0]) {
+		r.chunks = r.chunks[1:]
+	} else {
+		r.chunks[0] = r.chunks[0][n:]
+	}
+
+	r.stats.Buffered -= int64(n)
+
+	r.cond.Broadcast()
+
+	return n, nil
+}
+
+// CloseWithError ends the write side, readers see err (or io.EOF) once the
+// buffer is drained
+func (r *BufferedReader) CloseWithError(err error) error {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	if err == nil {
+		err = io.EOF
+	}
+
+	if r.err == nil {
+		r.err = err
+	}
+
+	r.cond.Broadcast()
+
+	return nil
+}
+
+// Close discards buffered data and stops the producer
+func (r *BufferedReader) Close() error {
+	r.lock.Lock()
+	r.closed = true
+	r.chunks = nil
+	r.stats.Buffered = 0
+	if r.err == nil {
+		r.err = io.ErrClosedPipe
+	}
+	r.cond.Broadcast()
+	r.lock.Unlock()
+
+	if r.closer != nil {
+		return r.closer.Close()
+	}
+
+	return nil
+}
+
+// bufferWriter ends the stream on Close instead of discarding it
+type bufferWriter struct {
+	*BufferedReader
+}
+
+func (w bufferWriter) Close() error {
+	return w.CloseWithError(nil)
+}
@@ -0,0 +1,138 @@
+package stdsdk
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"time"
+)
+
+type OptionsBuilder struct {
+	err  error
+	opts RequestOptions
+}
+
+func Opts() *OptionsBuilder {
+	return &OptionsBuilder{
+		opts: RequestOptions{
+			Headers: Headers{},
+			Params:  Params{},
+			Path:    Path{},
+			Query:   Query{},
+		},
+	}
+}
+
+func (b *OptionsBuilder) ArrayStyle(s ArrayStyle) *OptionsBuilder {
+	b.opts.ArrayStyle = s
+	return b
+}
+
+func (b *OptionsBuilder) Body(r io.Reader) *OptionsBuilder {
+	b.opts.Body = r
+	return b
+}
+
+func (b *OptionsBuilder) Buffer(size int, policy BufferPolicy) *OptionsBuilder {
+	b.opts.Buffer = &StreamBuffer{Policy: policy, Size: size}
+	return b
+}
+
+func (b *OptionsBuilder) ContentLength(n int64) *OptionsBuilder {
+	b.opts.ContentLength = n
+	return b
+}
+
+func (b *OptionsBuilder) Envelope(key string) *OptionsBuilder {
+	b.opts.Envelope = key
+	return b
+}
+
+func (b *OptionsBuilder) ExpectContinue() *OptionsBuilder {
+	b.opts.ExpectContinue = true
+	return b
+}
+
+func (b *OptionsBuilder) File(name string, data []byte) *OptionsBuilder {
+	if b.opts.Files == nil {
+		b.opts.Files = Files{}
+	}
+	b.opts.Files[name] = data
+	return b
+}
+
+func (b *OptionsBuilder) Header(k, v string) *OptionsBuilder {
+	b.opts.Headers[k] = v
+	return b
+}
+
+func (b *OptionsBuilder) JSON(v interface{}) *OptionsBuilder {
+	data, err := JSON.Marshal(v)
+	if err != nil {
+		b.err = err
+		return b
+	}
+
+	b.opts.Body = bytes.NewReader(data)
+	b.opts.Headers["Content-Type"] = "application/json"
+
+	return b
+}
+
+func (b *OptionsBuilder) Param(k string, v interface{}) *OptionsBuilder {
+	b.opts.Params[k] = v
+	return b
+}
+
+func (b *OptionsBuilder) Path(k string, v interface{}) *OptionsBuilder {
+	b.opts.Path[k] = v
+	return b
+}
+
+func (b *OptionsBuilder) Prepare(fns ...PrepareFunc) *OptionsBuilder {
+	b.opts.Prepare = append(b.opts.Prepare, fns...)
+	return b
+}
+
+func (b *OptionsBuilder) Priority(p Priority) *OptionsBuilder {
+	b.opts.Priority = p
+	return b
+}
+
+func (b *OptionsBuilder) Query(k string, v interface{}) *OptionsBuilder {
+	b.opts.Query[k] = v
+	return b
+}
+
+func (b *OptionsBuilder) ResponseHeaders(h *http.Header) *OptionsBuilder {
+	b.opts.ResponseHeaders = h
+	return b
+}
+
+func (b *OptionsBuilder) Schema(s *Schema) *OptionsBuilder {
+	b.opts.Schema = s
+	return b
+}
+
+func (b *OptionsBuilder) Timeout(d time.Duration) *OptionsBuilder {
+	b.opts.Timeout = d
+	return b
+}
+
+func (b *OptionsBuilder) TrailerError(header string) *OptionsBuilder {
+	b.opts.TrailerError = header
+	return b
+}
+
+func (b *OptionsBuilder) UseNumber() *OptionsBuilder {
+	b.opts.UseNumber = true
+	return b
+}
+
+func (b *OptionsBuilder) Build() (RequestOptions, error) {
+	if b.err != nil {
+		return RequestOptions{}, b.err
+	}
+
+	return b.opts.Clone(), nil
+}
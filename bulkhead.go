@@ -0,0 +1,83 @@
+package stdsdk
+
+import (
+	"context"
+	"fmt"
+	"sync"
+)
+
+// Bulkheads caps in-flight requests per host, share one value between
+// clients so a slow dependency cannot starve the others
+type Bulkheads struct {
+	Default  int
+	FailFast bool
+	Hosts    map[string]int
+
+	lock  sync.Mutex
+	slots map[string]chan struct{}
+}
+
+type BulkheadFullError struct {
+	Host  string
+	Limit int
+}
+
+func (e *BulkheadFullError) Error() string {
+	return fmt.Sprintf("too many requests in flight to %s (limit %d)", e.Host, e.Limit)
+}
+
+func (b *Bulkheads) Acquire(ctx context.Context, host string) (func(), error) {
+	slots := b.host(host)
+	if slots == nil {
+		return func() {}, nil
+	}
+
+	if b.FailFast {
+		select {
+		case slots <- struct{}{}:
+		default:
+			return nil, &BulkheadFullError{Host: host, Limit: cap(slots)}
+		}
+	} else {
+		select {
+		case slots <- struct{}{}:
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+
+	var once sync.Once
+
+	return func() {
+		once.Do(func() { <-slots })
+	}, nil
+}
+
+func (b *Bulkheads) host(host string) chan struct{} {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+
+	if s, ok := b.slots[host]; ok {
+		return s
+	}
+
+	n, ok := b.Hosts[host]
+	if !ok {
+		n = b.Default
+	}
+
+	var s chan struct{}
+
+	// zero means unlimited
+	if n > 0 {
+		s = make(chan struct{}, n)
+	}
+
+	if b.slots == nil {
+		b.slots = map[string]chan struct{}{}
+	}
+
+	b.slots[host] = s
+
+	return s
+}
@@ -0,0 +1,145 @@
+package stdsdk_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/liamdawson/stdsdk"
+)
+
+func TestBulkheadReleasedAfterErrorResponse(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/fail" {
+			w.WriteHeader(500)
+			w.Write([]byte(`{"error":"boom"}`))
+		}
+	}))
+	defer ts.Close()
+
+	c, err := stdsdk.New(ts.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	c.Bulkheads = &stdsdk.Bulkheads{Default: 1, FailFast: true}
+
+	if err := c.Get("/fail", stdsdk.RequestOptions{}, nil); err == nil {
+		t.Fatal("expected error response")
+	}
+
+	if err := c.Get("/", stdsdk.RequestOptions{}, nil); err != nil {
+		t.Errorf("request after error response: %v", err)
+	}
+}
+
+func TestBulkheadReleasedAfterAuthenticatorRetry(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") == "" {
+			w.WriteHeader(401)
+			w.Write([]byte("unauthorized"))
+		}
+	}))
+	defer ts.Close()
+
+	c, err := stdsdk.New(ts.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	c.Bulkheads = &stdsdk.Bulkheads{Default: 1, FailFast: true}
+	c.Authenticator = func(c *stdsdk.Client, res *http.Response) (http.Header, error) {
+		return http.Header{"Authorization": {"Bearer t"}}, nil
+	}
+
+	for i := 0; i < 2; i++ {
+		if err := c.Get("/", stdsdk.RequestOptions{}, nil); err != nil {
+			t.Fatalf("request %d: %v", i, err)
+		}
+	}
+}
+
+func TestBulkheadLimit(t *testing.T) {
+	block := make(chan struct{})
+	started := make(chan struct{}, 1)
+
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/slow" {
+			started <- struct{}{}
+			<-block
+		}
+	}))
+	defer ts.Close()
+
+	c, err := stdsdk.New(ts.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	b := &stdsdk.Bulkheads{Default: 1, FailFast: true}
+	c.Bulkheads = b
+
+	slow := make(chan error)
+
+	go func() {
+		slow <- c.Get("/slow", stdsdk.RequestOptions{}, nil)
+	}()
+
+	<-started
+
+	var full *stdsdk.BulkheadFullError
+
+	if err := c.Get("/", stdsdk.RequestOptions{}, nil); !errors.As(err, &full) || full.Limit != 1 {
+		t.Errorf("second request: err = %v, want bulkhead full", err)
+	}
+
+	// without FailFast the request waits for the slot
+	b.FailFast = false
+
+	waited := make(chan error)
+
+	go func() {
+		waited <- c.Get("/", stdsdk.RequestOptions{}, nil)
+	}()
+
+	select {
+	case err := <-waited:
+		t.Fatalf("request did not wait for the slot: %v", err)
+	case <-time.After(20 * time.Millisecond):
+	}
+
+	close(block)
+
+	if err := <-slow; err != nil {
+		t.Fatal(err)
+	}
+
+	if err := <-waited; err != nil {
+		t.Errorf("waiting request: %v", err)
+	}
+}
+
+func TestBulkheadHosts(t *testing.T) {
+	b := &stdsdk.Bulkheads{Default: 1, FailFast: true, Hosts: map[string]int{"wide": 2, "open": 0}}
+
+	for host, n := range map[string]int{"narrow": 1, "wide": 2} {
+		for i := 0; i < n; i++ {
+			if _, err := b.Acquire(context.Background(), host); err != nil {
+				t.Fatalf("%s slot %d: %v", host, i, err)
+			}
+		}
+
+		if _, err := b.Acquire(context.Background(), host); err == nil {
+			t.Errorf("%s: expected bulkhead full after %d", host, n)
+		}
+	}
+
+	for i := 0; i < 10; i++ {
+		if _, err := b.Acquire(context.Background(), "open"); err != nil {
+			t.Fatalf("unlimited host: %v", err)
+		}
+	}
+}
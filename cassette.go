@@ -0,0 +1,253 @@
+package stdsdk
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"gopkg.in/yaml.v3"
+)
+
+type CassetteMode int
+
+const (
+	CassetteReplay CassetteMode = iota
+	CassetteRecord
+)
+
+type Cassette struct {
+	Interactions []*Interaction
+	Match        MatchFunc
+	Mode         CassetteMode
+	Path         string
+	Transport    http.RoundTripper
+
+	lock sync.Mutex
+	used map[*Interaction]bool
+}
+
+type Interaction struct {
+	Request  CassetteRequest  `json:"request"`
+	Response CassetteResponse `json:"response"`
+}
+
+type CassetteRequest struct {
+	Method   string      `json:"method"`
+	Path     string      `json:"path"`
+	Query    string      `json:"query"`
+	Header   http.Header `json:"header"`
+	BodyHash string      `json:"body_hash"`
+}
+
+type CassetteResponse struct {
+	StatusCode int         `json:"status_code"`
+	Header     http.Header `json:"header"`
+	Body       []byte      `json:"body"`
+}
+
+type MatchFunc func(req CassetteRequest, i *Interaction) bool
+
+func DefaultMatch(req CassetteRequest, i *Interaction) bool {
+	return req.Method == i.Request.Method &&
+		req.Path == i.Request.Path &&
+		req.Query == i.Request.Query &&
+		req.BodyHash == i.Request.BodyHash
+}
+
+func LoadCassette(path string) (*Cassette, error) {
+	c := &Cassette{Mode: CassetteReplay, Path: path}
+
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	if cassetteYAML(path) {
+		if data, err = yamlToJSON(data); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := json.Unmarshal(data, &c.Interactions); err != nil {
+		return nil, err
+	}
+
+	return c, nil
+}
+
+// yaml cassettes share the json schema, bodies are base64 in both
+func cassetteYAML(path string) bool {
+	ext := strings.ToLower(filepath.Ext(path))
+	return ext == ".yaml" || ext == ".yml"
+}
+
+func NewCassetteRecorder(path string) *Cassette {
+	return &Cassette{Mode: CassetteRecord, Path: path}
+}
+
+// RoundTrip hashes the body from a copy of req, the original is left as
+// the RoundTripper contract requires
+func (c *Cassette) RoundTrip(req *http.Request) (*http.Response, error) {
+	var body []byte
+
+	if req.Body != nil && req.Body != http.NoBody {
+		data, err := ioutil.ReadAll(req.Body)
+		req.Body.Close()
+		if err != nil {
+			return nil, err
+		}
+
+		body = data
+
+		r := req.Clone(req.Context())
+		r.Body = ioutil.NopCloser(bytes.NewReader(data))
+		r.GetBody = func() (io.ReadCloser, error) {
+			return ioutil.NopCloser(bytes.NewReader(data)), nil
+		}
+		req = r
+	}
+
+	sum := sha256.Sum256(body)
+
+	// recordings and live requests are redacted alike so they still match
+	q := url.Values{}
+
+	for k, vs := range req.URL.Query() {
+		for _, v := range vs {
+			q.Add(k, redactParam(req, k, v))
+		}
+	}
+
+	cr := CassetteRequest{
+		Method:   req.Method,
+		Path:     req.URL.Path,
+		Query:    q.Encode(),
+		Header:   redactHeader(req.Header, sensitiveFor(req)),
+		BodyHash: hex.EncodeToString(sum[:]),
+	}
+
+	switch c.Mode {
+	case CassetteRecord:
+		return c.record(req, cr)
+	default:
+		return c.replay(req, cr)
+	}
+}
+
+func (c *Cassette) Save() error {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	data, err := json.MarshalIndent(c.Interactions, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	if cassetteYAML(c.Path) {
+		var v interface{}
+
+		if err := json.Unmarshal(data, &v); err != nil {
+			return err
+		}
+
+		if data, err = yaml.Marshal(v); err != nil {
+			return err
+		}
+	}
+
+	return ioutil.WriteFile(c.Path, data, 0644)
+}
+
+func (c *Cassette) record(req *http.Request, cr CassetteRequest) (*http.Response, error) {
+	t := c.Transport
+	if t == nil {
+		t = DefaultClient.Transport
+	}
+
+	res, err := t.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := ioutil.ReadAll(res.Body)
+	res.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+
+	res.Body = ioutil.NopCloser(bytes.NewReader(data))
+
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	c.Interactions = append(c.Interactions, &Interaction{
+		Request: cr,
+		Response: CassetteResponse{
+			StatusCode: res.StatusCode,
+			Header:     redactHeader(res.Header, sensitiveFor(req)),
+			Body:       data,
+		},
+	})
+
+	return res, nil
+}
+
+func (c *Cassette) replay(req *http.Request, cr CassetteRequest) (*http.Response, error) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	match := c.Match
+	if match == nil {
+		match = DefaultMatch
+	}
+
+	if c.used == nil {
+		c.used = map[*Interaction]bool{}
+	}
+
+	for _, i := range c.Interactions {
+		if c.used[i] || !match(cr, i) {
+			continue
+		}
+
+		c.used[i] = true
+
+		return &http.Response{
+			Status:        fmt.Sprintf("%d %s", i.Response.StatusCode, http.StatusText(i.Response.StatusCode)),
+			StatusCode:    i.Response.StatusCode,
+			Proto:         "HTTP/1.1",
+			ProtoMajor:    1,
+			ProtoMinor:    1,
+			Header:        i.Response.Header,
+			Body:          ioutil.NopCloser(bytes.NewReader(i.Response.Body)),
+			ContentLength: int64(len(i.Response.Body)),
+			Request:       req,
+		}, nil
+	}
+
+	return nil, fmt.Errorf("no cassette interaction for %s %s", req.Method, req.URL.Path)
+}
+
+func redactHeader(h http.Header, sensitive func(string) bool) http.Header {
+	r := http.Header{}
+
+	for k, vs := range h {
+		for _, v := range vs {
+			if sensitive(k) {
+				v = redacted
+			}
+			r.Add(k, v)
+		}
+	}
+
+	return r
+}
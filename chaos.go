@@ -0,0 +1,120 @@
+package stdsdk
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"math/rand"
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+// WebsocketChaos injects faults into websocket sessions, it exists to
+// exercise reconnect and resume handling in tests and must not be set in
+// production. Probabilities are per frame
+type WebsocketChaos struct {
+	CorruptClose float64
+	Delay        float64
+	Disconnect   float64
+	MaxDelay     time.Duration
+	Seed         int64
+
+	lock sync.Mutex
+	rng  *rand.Rand
+}
+
+type ChaosError struct {
+	Fault string
+}
+
+func (e *ChaosError) Error() string {
+	return fmt.Sprintf("websocket chaos: %s", e.Fault)
+}
+
+func (wc *WebsocketChaos) roll(clock Clock, p float64) bool {
+	if p <= 0 {
+		return false
+	}
+
+	wc.lock.Lock()
+	defer wc.lock.Unlock()
+
+	if wc.rng == nil {
+		seed := wc.Seed
+		if seed == 0 {
+			seed = clock.Now().UnixNano()
+		}
+		wc.rng = rand.New(rand.NewSource(seed))
+	}
+
+	return wc.rng.Float64() < p
+}
+
+func (wc *WebsocketChaos) delay(clock Clock) {
+	if wc.MaxDelay <= 0 || !wc.roll(clock, wc.Delay) {
+		return
+	}
+
+	wc.lock.Lock()
+	d := time.Duration(wc.rng.Int63n(int64(wc.MaxDelay)))
+	wc.lock.Unlock()
+
+	clock.Sleep(context.Background(), d)
+}
+
+type chaosConn struct {
+	wsConn
+	chaos  *WebsocketChaos
+	clock  Clock
+	closed error
+}
+
+func (c *chaosConn) NextReader() (int, io.Reader, error) {
+	if c.closed != nil {
+		return 0, nil, c.closed
+	}
+
+	c.chaos.delay(c.clock)
+
+	if c.chaos.roll(c.clock, c.chaos.Disconnect) {
+		return 0, nil, &ChaosError{Fault: "disconnect"}
+	}
+
+	code, r, err := c.wsConn.NextReader()
+	if err != nil {
+		return code, r, err
+	}
+
+	if code == websocket.TextMessage && c.chaos.roll(c.clock, c.chaos.CorruptClose) {
+		return 0, nil, c.corruptClose()
+	}
+
+	return code, r, nil
+}
+
+// corruptClose sends the peer a close frame with a code that is invalid on
+// the wire and fails the session with the protocol error close a conforming
+// endpoint reports for one, later reads keep failing
+func (c *chaosConn) corruptClose() error {
+	if cw, ok := c.wsConn.(interface {
+		WriteControl(messageType int, data []byte, deadline time.Time) error
+	}); ok {
+		cw.WriteControl(websocket.CloseMessage, websocket.FormatCloseMessage(999, ""), time.Now().Add(time.Second))
+	}
+
+	c.closed = &websocket.CloseError{Code: websocket.CloseProtocolError, Text: "websocket chaos: bad close code 999"}
+
+	return c.closed
+}
+
+func (c *chaosConn) WriteMessage(code int, data []byte) error {
+	c.chaos.delay(c.clock)
+
+	if c.chaos.roll(c.clock, c.chaos.Disconnect) {
+		return &ChaosError{Fault: "disconnect"}
+	}
+
+	return c.wsConn.WriteMessage(code, data)
+}
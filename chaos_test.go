@@ -0,0 +1,67 @@
+package stdsdk_test
+
+import (
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/liamdawson/stdsdk"
+)
+
+func TestWebsocketChaosCorruptClose(t *testing.T) {
+	received := make(chan error, 1)
+
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ws, err := (&websocket.Upgrader{}).Upgrade(w, r, nil)
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		defer ws.Close()
+
+		ws.WriteMessage(websocket.TextMessage, []byte("hello"))
+
+		for {
+			if _, _, err := ws.ReadMessage(); err != nil {
+				received <- err
+				return
+			}
+		}
+	}))
+	defer ts.Close()
+
+	c, err := stdsdk.New(ts.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	c.WebsocketChaos = &stdsdk.WebsocketChaos{CorruptClose: 1, Seed: 1}
+
+	r, err := c.Websocket("/", stdsdk.RequestOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+
+	_, err = ioutil.ReadAll(r)
+
+	var ce *websocket.CloseError
+
+	if !errors.As(err, &ce) || ce.Code != websocket.CloseProtocolError {
+		t.Errorf("client err = %v, want protocol error close", err)
+	}
+
+	select {
+	case err := <-received:
+		if err == nil || !strings.Contains(err.Error(), "bad close code 999") {
+			t.Errorf("server err = %v, want bad close code", err)
+		}
+	case <-time.After(time.Second):
+		t.Error("server never saw the close frame")
+	}
+}
@@ -1,15 +1,15 @@
 package stdsdk
 
 import (
+	"bytes"
 	"context"
-	"crypto/tls"
-	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"io/ioutil"
-	"net"
 	"net/http"
 	"net/url"
+	"strconv"
 	"strings"
 	"time"
 
@@ -20,32 +20,81 @@ const (
 	sortableTime = "20060102.150405.000000000"
 )
 
+type API interface {
+	Delete(path string, opts RequestOptions, out interface{}) error
+	DeleteStream(path string, opts RequestOptions) (*Response, error)
+	Get(path string, opts RequestOptions, out interface{}) error
+	GetStream(path string, opts RequestOptions) (*Response, error)
+	Head(path string, opts RequestOptions, out *bool) error
+	HeadMetadata(path string, opts RequestOptions) (*Metadata, error)
+	Options(path string, opts RequestOptions, out interface{}) error
+	Post(path string, opts RequestOptions, out interface{}) error
+	PostStream(path string, opts RequestOptions) (*Response, error)
+	Put(path string, opts RequestOptions, out interface{}) error
+	PutStream(path string, opts RequestOptions) (*Response, error)
+	Websocket(path string, opts RequestOptions) (io.ReadCloser, error)
+}
+
+var _ API = &Client{}
+
 type Authenticator func(c *Client, w *http.Response) (http.Header, error)
 
+// A Client is safe for concurrent use once configured, its fields must not
+// be modified while requests are in flight. Use WithContext, Sub or a copy
+// to derive clients with different settings.
 type Client struct {
-	Authenticator Authenticator
-	Endpoint      *url.URL
-	Headers       HeadersFunc
-
-	ctx context.Context
+	APIVersion              *APIVersion
+	ArrayStyle              ArrayStyle
+	Auth                    Auth
+	Authenticator           Authenticator
+	Bulkheads               *Bulkheads
+	Clock                   Clock
+	Coalesce                bool
+	ConnRetries             int
+	Debug                   *Debug
+	DeadlineHeader          string
+	DefaultHeaders          Headers
+	DefaultQuery            Query
+	DiscoveryPath           string
+	Endpoint                *url.URL
+	Envelope                string
+	ErrorDecoder            func(res *http.Response) error
+	ExpectContinueThreshold int64
+	Headers                 HeadersFunc
+	HealthPath              string
+	Limiter                 Limiter
+	Metrics                 Metrics
+	OpenAPI                 *OpenAPI
+	Password                string
+	Prepare                 []PrepareFunc
+	Recorder                *HARRecorder
+	Schemas                 map[string]*Schema
+	SensitiveHeaders        []string
+	SlowLog                 *SlowLog
+	StrictSchemas           bool
+	Timeout                 time.Duration
+	Tracer                  Tracer
+	Transport               http.RoundTripper
+	UseNumber               bool
+	UserAgent               string
+	Username                string
+	WebsocketChaos          *WebsocketChaos
+
+	ctx       context.Context
+	discovery *discoveryCache
+	flights   *flightGroup
+	skew      *clockSkew
+	stats     *clientStats
+	sub       string
 }
 
 type HeadersFunc func() http.Header
 
+// PrepareFunc adjusts a request after headers and auth have been applied
+type PrepareFunc func(req *http.Request) error
+
 var DefaultClient = &http.Client{
-	Transport: &http.Transport{
-		DialContext: (&net.Dialer{
-			Timeout:   30 * time.Second,
-			KeepAlive: 10 * time.Second,
-		}).DialContext,
-		MaxIdleConns:          100,
-		IdleConnTimeout:       90 * time.Second,
-		TLSHandshakeTimeout:   10 * time.Second,
-		ExpectContinueTimeout: 1 * time.Second,
-		TLSClientConfig: &tls.Config{
-			InsecureSkipVerify: true,
-		},
-	},
+	Transport: NewTransport(TransportOptions{}),
 }
 
 func New(endpoint string) (*Client, error) {
@@ -55,8 +104,13 @@ func New(endpoint string) (*Client, error) {
 	}
 
 	c := &Client{
-		Endpoint: u,
-		ctx:      context.Background(),
+		Debug:     debugFromEnv(),
+		Endpoint:  u,
+		ctx:       context.Background(),
+		discovery: &discoveryCache{},
+		flights:   &flightGroup{},
+		skew:      &clockSkew{},
+		stats:     &clientStats{},
 	}
 
 	c.Headers = func() http.Header { return http.Header{} }
@@ -72,9 +126,19 @@ func (c *Client) Head(path string, opts RequestOptions, out *bool) error {
 
 	res, err := c.HandleRequest(req)
 	if err != nil {
+		// a missing resource is an answer rather than a failure
+		var re *ResponseError
+		if errors.As(err, &re) && re.Status == 404 {
+			*out = false
+			return nil
+		}
 		return err
 	}
 
+	res.Body.Close()
+
+	captureResponse(res, opts)
+
 	switch res.StatusCode / 100 {
 	case 2:
 		*out = true
@@ -85,6 +149,22 @@ func (c *Client) Head(path string, opts RequestOptions, out *bool) error {
 	return nil
 }
 
+func (c *Client) HeadMetadata(path string, opts RequestOptions) (*Metadata, error) {
+	req, err := c.Request("HEAD", path, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	res, err := c.HandleRequest(req)
+	if err != nil {
+		return nil, err
+	}
+
+	defer res.Body.Close()
+
+	return responseMetadata(res), nil
+}
+
 func (c *Client) Options(path string, opts RequestOptions, out interface{}) error {
 	req, err := c.Request("OPTIONS", path, opts)
 	if err != nil {
@@ -96,19 +176,39 @@ func (c *Client) Options(path string, opts RequestOptions, out interface{}) erro
 		return err
 	}
 
-	return unmarshalReader(res.Body, out)
-}
-
-func (c *Client) GetStream(path string, opts RequestOptions) (*http.Response, error) {
-	req, err := c.Request("GET", path, opts)
-	if err != nil {
-		return nil, err
+	if caps, ok := out.(*Capabilities); ok {
+		defer res.Body.Close()
+		captureResponse(res, opts)
+		*caps = *responseCapabilities(res)
+		return nil
 	}
 
-	return c.HandleRequest(req)
+	return c.decodeResponse(res, out, opts)
+}
+
+func (c *Client) GetStream(path string, opts RequestOptions) (*Response, error) {
+	return c.stream("GET", path, opts)
 }
 
 func (c *Client) Get(path string, opts RequestOptions, out interface{}) error {
+	if c.Coalesce {
+		req, err := c.Request("GET", path, opts)
+		if err != nil {
+			return err
+		}
+
+		res, err := c.coalesced(req)
+		if err != nil {
+			return err
+		}
+
+		defer res.Body.Close()
+
+		trailerErrors(res, opts)
+
+		return c.decodeResponse(res, out, opts)
+	}
+
 	res, err := c.GetStream(path, opts)
 	if err != nil {
 		return err
@@ -116,21 +216,11 @@ func (c *Client) Get(path string, opts RequestOptions, out interface{}) error {
 
 	defer res.Body.Close()
 
-	return unmarshalReader(res.Body, out)
+	return c.decodeResponse(res.Response, out, opts)
 }
 
-func (c *Client) PostStream(path string, opts RequestOptions) (*http.Response, error) {
-	req, err := c.Request("POST", path, opts)
-	if err != nil {
-		return nil, err
-	}
-
-	res, err := c.HandleRequest(req)
-	if err != nil {
-		return nil, err
-	}
-
-	return res, nil
+func (c *Client) PostStream(path string, opts RequestOptions) (*Response, error) {
+	return c.stream("POST", path, opts)
 }
 
 func (c *Client) Post(path string, opts RequestOptions, out interface{}) error {
@@ -141,16 +231,11 @@ func (c *Client) Post(path string, opts RequestOptions, out interface{}) error {
 
 	defer res.Body.Close()
 
-	return unmarshalReader(res.Body, out)
+	return c.decodeResponse(res.Response, out, opts)
 }
 
-func (c *Client) PutStream(path string, opts RequestOptions) (*http.Response, error) {
-	req, err := c.Request("PUT", path, opts)
-	if err != nil {
-		return nil, err
-	}
-
-	return c.HandleRequest(req)
+func (c *Client) PutStream(path string, opts RequestOptions) (*Response, error) {
+	return c.stream("PUT", path, opts)
 }
 
 func (c *Client) Put(path string, opts RequestOptions, out interface{}) error {
@@ -161,27 +246,49 @@ func (c *Client) Put(path string, opts RequestOptions, out interface{}) error {
 
 	defer res.Body.Close()
 
-	return unmarshalReader(res.Body, out)
+	return c.decodeResponse(res.Response, out, opts)
 }
 
-func (c *Client) Delete(path string, opts RequestOptions, out interface{}) error {
-	req, err := c.Request("DELETE", path, opts)
+func (c *Client) stream(method, path string, opts RequestOptions) (*Response, error) {
+	req, err := c.Request(method, path, opts)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
 	res, err := c.HandleRequest(req)
+	if err != nil {
+		return nil, err
+	}
+
+	trailerErrors(res, opts)
+
+	if opts.Buffer != nil {
+		res.Body = bufferStream(opts.Buffer, res.Body)
+	}
+
+	return &Response{Response: res}, nil
+}
+
+func (c *Client) DeleteStream(path string, opts RequestOptions) (*Response, error) {
+	return c.stream("DELETE", path, opts)
+}
+
+func (c *Client) Delete(path string, opts RequestOptions, out interface{}) error {
+	res, err := c.DeleteStream(path, opts)
 	if err != nil {
 		return err
 	}
 
-	return unmarshalReader(res.Body, out)
+	defer res.Body.Close()
+
+	return c.decodeResponse(res.Response, out, opts)
 }
 
 func (c *Client) Websocket(path string, opts RequestOptions) (io.ReadCloser, error) {
-	var u url.URL
-
-	u = *c.Endpoint
+	u, err := c.URL(path, opts)
+	if err != nil {
+		return nil, err
+	}
 
 	u.Scheme = "wss"
 
@@ -189,10 +296,7 @@ func (c *Client) Websocket(path string, opts RequestOptions) (io.ReadCloser, err
 		u.Scheme = "ws"
 	}
 
-	u.Path += path
-	u.User = nil
-
-	h := c.Headers()
+	h := c.headers()
 
 	h.Set("Origin", strings.ToLower(fmt.Sprintf("%s://%s", c.Endpoint.Scheme, c.Endpoint.Host)))
 
@@ -200,16 +304,20 @@ func (c *Client) Websocket(path string, opts RequestOptions) (io.ReadCloser, err
 		h.Set(k, v)
 	}
 
-	websocket.DefaultDialer.TLSClientConfig = &tls.Config{
-		InsecureSkipVerify: true,
+	if c.Auth != nil {
+		if err := c.Auth.Apply(c.ctx, &http.Request{Method: "GET", URL: u, Header: h}); err != nil {
+			return nil, err
+		}
 	}
 
-	ws, _, err := websocket.DefaultDialer.Dial(u.String(), h)
+	ws, err := c.dialWebsocket(u.String(), h)
 	if err != nil {
-		return nil, err
+		return nil, &RequestError{Err: c.redactError(err, h), Method: "GET", Path: path}
 	}
 
-	r, w := io.Pipe()
+	if c.WebsocketChaos != nil {
+		ws = &chaosConn{wsConn: ws, chaos: c.WebsocketChaos, clock: c.clock()}
+	}
 
 	or, ct, err := opts.Content()
 	if err != nil {
@@ -219,12 +327,26 @@ func (c *Client) Websocket(path string, opts RequestOptions) (io.ReadCloser, err
 	h.Set("Content-Type", ct)
 
 	go copyToWebsocket(c.ctx, ws, or)
+
+	if opts.Buffer != nil {
+		br := newBufferedReader(opts.Buffer, nil)
+		go copyFromWebsocket(c.ctx, bufferWriter{br}, ws)
+		return br, nil
+	}
+
+	r, w := io.Pipe()
+
 	go copyFromWebsocket(c.ctx, w, ws)
 
 	return r, nil
 }
 
-func copyToWebsocket(ctx context.Context, ws *websocket.Conn, r io.Reader) {
+type wsConn interface {
+	NextReader() (int, io.Reader, error)
+	WriteMessage(messageType int, data []byte) error
+}
+
+func copyToWebsocket(ctx context.Context, ws wsConn, r io.Reader) {
 	if r == nil {
 		return
 	}
@@ -232,7 +354,10 @@ func copyToWebsocket(ctx context.Context, ws *websocket.Conn, r io.Reader) {
 	// used as eof
 	defer ws.WriteMessage(websocket.BinaryMessage, []byte{})
 
-	buf := make([]byte, 1024)
+	bp := getCopyBuffer()
+	defer putCopyBuffer(bp)
+
+	buf := *bp
 
 	for {
 		select {
@@ -252,56 +377,208 @@ func copyToWebsocket(ctx context.Context, ws *websocket.Conn, r io.Reader) {
 	}
 }
 
-func copyFromWebsocket(ctx context.Context, w io.WriteCloser, ws *websocket.Conn) {
-	defer w.Close()
+func copyFromWebsocket(ctx context.Context, w io.WriteCloser, ws wsConn) {
+	var err error
+
+	// surface read failures to the reader so dropped sessions are not mistaken for eof
+	defer func() {
+		if cw, ok := w.(interface{ CloseWithError(error) error }); ok && err != nil {
+			cw.CloseWithError(err)
+			return
+		}
+		w.Close()
+	}()
 
 	for {
 		select {
 		case <-ctx.Done():
 			return
 		default:
-			code, data, err := ws.ReadMessage()
+			var code int
+			var mr io.Reader
+
+			code, mr, err = ws.NextReader()
 			switch err {
 			case io.EOF:
+				err = nil
 				return
 			case nil:
 				switch code {
 				case websocket.TextMessage:
-					w.Write(data)
+					if _, err = copyBuffered(w, mr); err != nil {
+						return
+					}
 				case websocket.BinaryMessage: // interpreted as eof
 					return
 				}
 			default:
+				if websocket.IsCloseError(err, websocket.CloseNormalClosure) {
+					err = nil
+				}
 				return
 			}
 		}
 	}
 }
 
-func (c *Client) Request(method, path string, opts RequestOptions) (*http.Request, error) {
+func (c *Client) URL(path string, opts RequestOptions) (*url.URL, error) {
+	opts.Query = c.query(opts.Query)
+
+	if opts.ArrayStyle == ArrayDefault {
+		opts.ArrayStyle = c.ArrayStyle
+	}
+
 	qs, err := opts.Querystring()
 	if err != nil {
 		return nil, err
 	}
 
-	r, ct, err := opts.Content()
+	ep, err := opts.ExpandPath(c.versionPath(path))
+	if err != nil {
+		return nil, err
+	}
+
+	ep = escapePath(ep)
+
+	p, err := url.PathUnescape(ep)
+	if err != nil {
+		return nil, err
+	}
+
+	u := &url.URL{
+		Scheme:   c.Endpoint.Scheme,
+		Host:     c.Endpoint.Host,
+		Path:     p,
+		RawPath:  ep,
+		RawQuery: qs,
+	}
+
+	return u, nil
+}
+
+// escapePath percent-encodes anything not valid in a path while leaving
+// existing escape sequences intact
+func escapePath(s string) string {
+	var b strings.Builder
+
+	for i := 0; i < len(s); i++ {
+		ch := s[i]
+
+		switch {
+		case ch == '%' && i+2 < len(s) && isHex(s[i+1]) && isHex(s[i+2]):
+			b.WriteByte(ch)
+		case ch >= 'a' && ch <= 'z', ch >= 'A' && ch <= 'Z', ch >= '0' && ch <= '9':
+			b.WriteByte(ch)
+		case strings.IndexByte("-._~/:@!$&'()*+,;=", ch) >= 0:
+			b.WriteByte(ch)
+		default:
+			fmt.Fprintf(&b, "%%%02X", ch)
+		}
+	}
+
+	return b.String()
+}
+
+func isHex(ch byte) bool {
+	return (ch >= '0' && ch <= '9') || (ch >= 'a' && ch <= 'f') || (ch >= 'A' && ch <= 'F')
+}
+
+func rawPath(u *url.URL) string {
+	if u.RawPath != "" {
+		return u.RawPath
+	}
+
+	return u.Path
+}
+
+func joinPath(base, path string) string {
+	if path == "" {
+		return base
+	}
+
+	if !strings.HasPrefix(path, "/") {
+		path = "/" + path
+	}
+
+	return strings.TrimSuffix(base, "/") + path
+}
+
+// relativePath strips the endpoint path from u
+func (c *Client) relativePath(u *url.URL) string {
+	p := u.Path
+
+	if c.Endpoint != nil {
+		p = strings.TrimPrefix(p, strings.TrimSuffix(c.Endpoint.Path, "/"))
+	}
+
+	return "/" + strings.TrimPrefix(p, "/")
+}
+
+func (c *Client) Request(method, path string, opts RequestOptions) (*http.Request, error) {
+	u, err := c.URL(path, opts)
 	if err != nil {
 		return nil, err
 	}
 
-	endpoint := fmt.Sprintf("%s://%s%s%s?%s", c.Endpoint.Scheme, c.Endpoint.Host, c.Endpoint.Path, path, qs)
+	r, ct, err := opts.Content()
+	if err != nil {
+		return nil, err
+	}
 
-	req, err := http.NewRequest(method, endpoint, r)
+	req, err := http.NewRequest(method, u.String(), r)
 	if err != nil {
 		return nil, err
 	}
 
-	req = req.WithContext(c.ctx)
+	if opts.Body != nil && opts.ContentLength > 0 {
+		req.ContentLength = opts.ContentLength
+	}
+
+	expect := opts.ExpectContinue
+
+	if t := c.ExpectContinueThreshold; t > 0 && !expect && req.Body != nil && req.Body != http.NoBody {
+		if req.ContentLength <= 0 {
+			if err := peekBody(req, t); err != nil {
+				return nil, err
+			}
+		}
+
+		expect = req.ContentLength < 0 || req.ContentLength >= t
+	}
+
+	if req.Body != nil && expect {
+		req.Header.Set("Expect", "100-continue")
+	}
+
+	ctx := c.ctx
+
+	if opts.Priority != PriorityNormal {
+		ctx = WithPriority(ctx, opts.Priority)
+	}
+
+	// the total timeout covers reading the body, streams need a larger one.
+	// It starts when the request is sent so an unsent request holds no timer
+	if opts.Timeout == 0 {
+		opts.Timeout = c.Timeout
+	}
+
+	if opts.Timeout > 0 {
+		ctx = context.WithValue(ctx, timeoutKey{}, opts.Timeout)
+	}
+
+	ctx = context.WithValue(ctx, templateKey{}, path)
+	ctx = withClock(ctx, c.ServerClock())
+
+	if opts.ResponseHeaders != nil {
+		ctx = context.WithValue(ctx, responseHeadersKey{}, opts.ResponseHeaders)
+	}
+
+	req = req.WithContext(ctx)
 
 	req.Header.Add("Accept", "*/*")
 	req.Header.Set("Content-Type", ct)
 
-	h := c.Headers()
+	h := c.headers()
 
 	for k := range h {
 		req.Header.Set(k, h.Get(k))
@@ -311,15 +588,156 @@ func (c *Client) Request(method, path string, opts RequestOptions) (*http.Reques
 		req.Header.Set(k, v)
 	}
 
+	if c.Auth != nil {
+		actx := ctx
+
+		if opts.Timeout > 0 {
+			var cancel context.CancelFunc
+			actx, cancel = context.WithTimeout(ctx, opts.Timeout)
+			defer cancel()
+		}
+
+		if err := c.Auth.Apply(actx, req); err != nil {
+			return nil, err
+		}
+	}
+
+	// request level functions run last so they can undo client level settings
+	for _, fn := range append(append([]PrepareFunc{}, c.Prepare...), opts.Prepare...) {
+		if err := fn(req); err != nil {
+			return nil, err
+		}
+	}
+
+	if c.OpenAPI != nil {
+		if err := c.OpenAPI.ValidateRequest(req, c.relativePath(req.URL)); err != nil {
+			return nil, err
+		}
+	}
+
 	return req, nil
 }
 
+// query merges the default query with q, a nil value in q removes the key
+func (c *Client) query(q Query) Query {
+	m := Query{}
+
+	for k, v := range c.DefaultQuery {
+		m[k] = v
+	}
+
+	for k, v := range q {
+		if v == nil {
+			delete(m, k)
+			continue
+		}
+		m[k] = v
+	}
+
+	return m
+}
+
+// userinfo in Endpoint is deprecated and never sent, use Username/Password
+func (c *Client) basicAuth() string {
+	if c.Username == "" && c.Password == "" {
+		return ""
+	}
+
+	return BasicAuth{Username: c.Username, Password: c.Password}.header()
+}
+
+func (c *Client) headers() http.Header {
+	h := http.Header{}
+
+	h.Set("User-Agent", c.userAgent())
+
+	if auth := c.basicAuth(); auth != "" {
+		h.Set("Authorization", auth)
+	}
+
+	if c.APIVersion != nil && c.APIVersion.Version != "" {
+		h.Set(c.APIVersion.header(), c.APIVersion.Version)
+	}
+
+	for k, v := range c.DefaultHeaders {
+		h.Set(k, v)
+	}
+
+	if c.Headers != nil {
+		for k, v := range c.Headers() {
+			h[http.CanonicalHeaderKey(k)] = v
+		}
+	}
+
+	return h
+}
+
 func (c *Client) HandleRequest(req *http.Request) (*http.Response, error) {
-	res, err := DefaultClient.Do(req)
+	req, cancel := withTimeout(req)
+
+	req, o := withOutcome(req)
+
+	req = c.withSensitive(req)
+
+	if c.Tracer != nil {
+		req = c.startSpan(req)
+	}
+
+	res, err := c.handleRequest(req)
+	if err != nil {
+		err = annotate(req, o, c.redactError(err, req.Header))
+	}
+
+	if c.Tracer != nil {
+		c.endSpan(req, o, err)
+	}
+
+	if cancel != nil {
+		if err != nil {
+			cancel()
+			return nil, err
+		}
+		res.Body = &cancelBody{ReadCloser: res.Body, cancel: cancel}
+	}
+
+	return res, err
+}
+
+func (c *Client) handleRequest(req *http.Request) (*http.Response, error) {
+	res, err := c.do(req)
+
+	for i := 0; err != nil && i < c.connRetries() && connReset(req, err); i++ {
+		c.retry(req)
+		if req, err = rewindRequest(req); err != nil {
+			return nil, err
+		}
+		res, err = c.do(req)
+	}
+
 	if err != nil {
 		return nil, err
 	}
 
+	if res.StatusCode == 401 && !retried(req) {
+		if uh, ok := c.Auth.(UnauthorizedHandler); ok {
+			retry, err := uh.OnUnauthorized(req.Context(), res)
+			if err != nil {
+				return nil, err
+			}
+			if retry {
+				res.Body.Close()
+				c.retry(req)
+				if req, err = retryRequest(req); err != nil {
+					return nil, err
+				}
+				if err := c.Auth.Apply(req.Context(), req); err != nil {
+					return nil, err
+				}
+				return c.handleRequest(req)
+			}
+		}
+	}
+
 	if res.StatusCode == 401 {
 		if c.Authenticator != nil {
 			hs, err := c.Authenticator(c, res)
@@ -333,11 +751,37 @@ func (c *Client) HandleRequest(req *http.Request) (*http.Response, error) {
 						req.Header.Add(k, s)
 					}
 				}
-				return c.HandleRequest(req)
+				// draining frees the connection and the bulkhead slot
+				io.Copy(ioutil.Discard, res.Body)
+				res.Body.Close()
+				c.retry(req)
+				return c.handleRequest(req)
 			}
 		}
 	}
 
+	// error responses never reach decode so the headers are captured here
+	if h, ok := req.Context().Value(responseHeadersKey{}).(*http.Header); ok {
+		*h = res.Header
+	}
+
+	if req.Context().Value(anyStatusKey{}) != nil {
+		return res, nil
+	}
+
+	if err := c.checkVersion(req, res); err != nil {
+		res.Body.Close()
+		return nil, err
+	}
+
+	// a nil result falls back to the default error handling
+	if c.ErrorDecoder != nil && res.StatusCode >= 400 {
+		if err := c.ErrorDecoder(res); err != nil {
+			res.Body.Close()
+			return nil, err
+		}
+	}
+
 	if err := responseError(res); err != nil {
 		return nil, err
 	}
@@ -345,6 +789,217 @@ func (c *Client) HandleRequest(req *http.Request) (*http.Response, error) {
 	return res, nil
 }
 
+func (c *Client) do(req *http.Request) (*http.Response, error) {
+	if c.DeadlineHeader != "" {
+		if dl, ok := req.Context().Deadline(); ok {
+			// deadlines are wall clock so a substituted clock does not apply
+			ms := time.Until(dl) / time.Millisecond
+			if ms < 0 {
+				ms = 0
+			}
+			req.Header.Set(c.DeadlineHeader, strconv.FormatInt(int64(ms), 10))
+		}
+	}
+
+	if c.Debug != nil {
+		c.Debug.request(req)
+	}
+
+	var reqBody []byte
+
+	recording := c.Recorder != nil && c.Recorder.Enabled()
+
+	if recording {
+		data, err := c.Recorder.bufferRequest(req)
+		if err != nil {
+			return nil, err
+		}
+		reqBody = data
+	}
+
+	var release func()
+
+	if c.Bulkheads != nil {
+		r, err := c.Bulkheads.Acquire(req.Context(), req.URL.Host)
+		if err != nil {
+			return nil, err
+		}
+		release = r
+	}
+
+	var done func(time.Duration, bool)
+
+	if c.Limiter != nil {
+		d, err := c.Limiter.Acquire(req.Context())
+		if err != nil {
+			if release != nil {
+				release()
+			}
+			return nil, err
+		}
+		done = d
+	}
+
+	var slow *slowTrace
+
+	if c.SlowLog != nil {
+		req, slow = c.SlowLog.trace(req)
+	}
+
+	started := c.clock().Now()
+
+	c.stats.begin(req)
+
+	res, err := c.httpClient().Do(req)
+
+	c.stats.end(res)
+
+	c.observeMetrics(req, res, err, c.clock().Now().Sub(started))
+
+	if slow != nil {
+		c.SlowLog.observe(slow, req, res, err, c.clock().Now().Sub(started))
+	}
+
+	if done != nil {
+		done(c.clock().Now().Sub(started), limitDropped(res, err))
+	}
+
+	if err != nil {
+		if release != nil {
+			release()
+		}
+		return nil, err
+	}
+
+	if d, ok := readIdleTimeouts.Load(c.httpClient().Transport); ok {
+		res.Body = &idleBody{ReadCloser: res.Body, timeout: d.(time.Duration)}
+	}
+
+	// the slot is held until the body is closed
+	if release != nil {
+		res.Body = &cancelBody{ReadCloser: res.Body, cancel: release}
+	}
+
+	observeOutcome(req, res)
+
+	c.skew.observe(res, started, c.clock().Now())
+
+	if c.Debug != nil {
+		c.Debug.response(res)
+	}
+
+	if recording {
+		c.Recorder.record(req, reqBody, res, started, c.clock().Now().Sub(started))
+	}
+
+	return res, nil
+}
+
+// peekBody reads up to limit bytes of a body of unknown length, a shorter
+// body becomes a buffered one of known length while a longer one is left
+// streaming with a length of -1
+func peekBody(req *http.Request, limit int64) error {
+	var buf bytes.Buffer
+
+	n, err := io.CopyN(&buf, req.Body, limit)
+	if err != nil && err != io.EOF {
+		return err
+	}
+
+	if n < limit {
+		req.Body.Close()
+		data := buf.Bytes()
+		req.ContentLength = n
+		req.Body = ioutil.NopCloser(bytes.NewReader(data))
+		req.GetBody = func() (io.ReadCloser, error) {
+			return ioutil.NopCloser(bytes.NewReader(data)), nil
+		}
+		return nil
+	}
+
+	req.ContentLength = -1
+	req.Body = struct {
+		io.Reader
+		io.Closer
+	}{io.MultiReader(&buf, req.Body), req.Body}
+
+	return nil
+}
+
+type timeoutKey struct{}
+
+// withTimeout starts the timeout set by Request, the key is cleared so
+// nested sends of the same request do not start another
+func withTimeout(req *http.Request) (*http.Request, context.CancelFunc) {
+	d, _ := req.Context().Value(timeoutKey{}).(time.Duration)
+	if d <= 0 {
+		return req, nil
+	}
+
+	ctx, cancel := context.WithTimeout(req.Context(), d)
+
+	return req.WithContext(context.WithValue(ctx, timeoutKey{}, time.Duration(0))), cancel
+}
+
+type retryKey struct{}
+
+func retried(req *http.Request) bool {
+	return req.Context().Value(retryKey{}) != nil
+}
+
+// retryRequest marks req as retried and rewinds its body when possible
+func retryRequest(req *http.Request) (*http.Request, error) {
+	return rewindRequest(req.WithContext(context.WithValue(req.Context(), retryKey{}, true)))
+}
+
+func rewindRequest(req *http.Request) (*http.Request, error) {
+	r := req.WithContext(req.Context())
+
+	if req.GetBody != nil {
+		body, err := req.GetBody()
+		if err != nil {
+			return nil, err
+		}
+		r.Body = body
+	}
+
+	return r, nil
+}
+
+type cancelBody struct {
+	io.ReadCloser
+	cancel context.CancelFunc
+}
+
+func (b *cancelBody) Close() error {
+	defer b.cancel()
+	return b.ReadCloser.Close()
+}
+
+func (c *Client) httpClient() *http.Client {
+	if c.Transport == nil {
+		return DefaultClient
+	}
+
+	hc := *DefaultClient
+	hc.Transport = c.Transport
+
+	return &hc
+}
+
+func (c *Client) Sub(prefix string) *Client {
+	d := *c
+	u := *c.Endpoint
+	u.RawPath = joinPath(rawPath(c.Endpoint), strings.TrimSuffix(prefix, "/"))
+	d.sub = joinPath(c.sub, strings.TrimSuffix(prefix, "/"))
+	u.Path = u.RawPath
+	if p, err := url.PathUnescape(u.RawPath); err == nil {
+		u.Path = p
+	}
+	d.Endpoint = &u
+	return &d
+}
+
 func (c *Client) WithContext(ctx context.Context) *Client {
 	d := *c
 	d.ctx = ctx
@@ -362,39 +1017,48 @@ func responseError(res *http.Response) error {
 		return nil
 	}
 
-	data, err := ioutil.ReadAll(res.Body)
-	if err != nil {
+	// closing releases the bulkhead slot and the in-flight count
+	defer res.Body.Close()
+
+	buf := getBuffer()
+	defer putBuffer(buf)
+
+	if _, err := buf.ReadFrom(res.Body); err != nil {
 		return err
 	}
 
+	data := buf.Bytes()
+
 	var e struct {
-		Error string
+		Detail string
+		Error  string
+		Title  string
 	}
 
-	if err := json.Unmarshal(data, &e); err == nil && e.Error != "" {
-		return fmt.Errorf(e.Error)
+	if err := JSON.Unmarshal(data, &e); err == nil {
+		// problem+json puts the message in detail, falling back to title
+		for _, m := range []string{e.Error, e.Detail, e.Title} {
+			if m != "" {
+				return &ResponseError{Message: m, Status: res.StatusCode}
+			}
+		}
 	}
 
 	msg := strings.TrimSpace(string(data))
 
 	if len(msg) > 0 {
-		return fmt.Errorf(msg)
+		return &ResponseError{Message: msg, Status: res.StatusCode}
 	}
 
-	return fmt.Errorf("response status %d", res.StatusCode)
+	return &ResponseError{Message: fmt.Sprintf("response status %d", res.StatusCode), Status: res.StatusCode}
 }
 
-func unmarshalReader(r io.ReadCloser, out interface{}) error {
-	defer r.Close()
-
-	if out == nil {
-		return nil
-	}
-
-	data, err := ioutil.ReadAll(r)
-	if err != nil {
-		return err
-	}
+// ResponseError is returned for error statuses the ErrorDecoder did not handle
+type ResponseError struct {
+	Message string
+	Status  int
+}
 
-	return json.Unmarshal(data, out)
+func (e *ResponseError) Error() string {
+	return e.Message
 }
@@ -0,0 +1,162 @@
+package stdsdk
+
+import (
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// PoolKey identifies a pooled client, Credentials should be a tenant id or
+// a hash rather than the secret itself since keys are kept in memory
+type PoolKey struct {
+	Credentials string
+	Endpoint    string
+}
+
+// ClientPool caches clients for multi-tenant callers, every client shares
+// one transport so connection limits apply across the whole pool
+type ClientPool struct {
+	Clock            Clock
+	IdleTimeout      time.Duration
+	New              func(key PoolKey) (*Client, error)
+	TransportOptions TransportOptions
+
+	lock      sync.Mutex
+	building  map[PoolKey]*poolCall
+	clients   map[PoolKey]*pooledClient
+	transport http.RoundTripper
+}
+
+type poolCall struct {
+	client *Client
+	done   chan struct{}
+	err    error
+}
+
+type pooledClient struct {
+	client *Client
+	used   time.Time
+}
+
+func (p *ClientPool) clock() Clock {
+	if p.Clock != nil {
+		return p.Clock
+	}
+
+	return SystemClock
+}
+
+// Get returns the cached client for key, creating it with New if needed. New
+// runs outside the lock, concurrent calls for one key share a single build
+func (p *ClientPool) Get(key PoolKey) (*Client, error) {
+	now := p.clock().Now()
+
+	p.lock.Lock()
+
+	p.evict(now)
+
+	if pc, ok := p.clients[key]; ok {
+		pc.used = now
+		p.lock.Unlock()
+		return pc.client, nil
+	}
+
+	if call, ok := p.building[key]; ok {
+		p.lock.Unlock()
+		<-call.done
+		return call.client, call.err
+	}
+
+	if p.building == nil {
+		p.building = map[PoolKey]*poolCall{}
+	}
+
+	call := &poolCall{done: make(chan struct{})}
+	p.building[key] = call
+
+	p.lock.Unlock()
+
+	call.client, call.err = p.build(key)
+
+	p.lock.Lock()
+
+	delete(p.building, key)
+
+	if call.err == nil {
+		if p.clients == nil {
+			p.clients = map[PoolKey]*pooledClient{}
+		}
+		p.clients[key] = &pooledClient{client: call.client, used: now}
+	}
+
+	p.lock.Unlock()
+
+	close(call.done)
+
+	return call.client, call.err
+}
+
+func (p *ClientPool) build(key PoolKey) (*Client, error) {
+	var c *Client
+	var err error
+
+	if p.New != nil {
+		c, err = p.New(key)
+	} else {
+		c, err = New(key.Endpoint)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if c == nil {
+		return nil, fmt.Errorf("client pool: no client for %s", key.Endpoint)
+	}
+
+	if c.Transport == nil {
+		c.Transport = p.sharedTransport()
+	}
+
+	return c, nil
+}
+
+func (p *ClientPool) sharedTransport() http.RoundTripper {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	if p.transport == nil {
+		p.transport = NewTransport(p.TransportOptions)
+	}
+
+	return p.transport
+}
+
+func (p *ClientPool) Remove(key PoolKey) {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	delete(p.clients, key)
+}
+
+func (p *ClientPool) Len() int {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	p.evict(p.clock().Now())
+
+	return len(p.clients)
+}
+
+// evict drops clients unused for IdleTimeout, their connections stay in
+// the shared transport until its own idle timeout closes them
+func (p *ClientPool) evict(now time.Time) {
+	if p.IdleTimeout <= 0 {
+		return
+	}
+
+	for k, pc := range p.clients {
+		if now.Sub(pc.used) > p.IdleTimeout {
+			delete(p.clients, k)
+		}
+	}
+}
@@ -0,0 +1,80 @@
+package stdsdk_test
+
+import (
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/liamdawson/stdsdk"
+)
+
+func TestClientPoolBuildsOutsideLock(t *testing.T) {
+	release := make(chan struct{})
+
+	var builds int32
+
+	p := &stdsdk.ClientPool{}
+
+	p.New = func(key stdsdk.PoolKey) (*stdsdk.Client, error) {
+		atomic.AddInt32(&builds, 1)
+
+		if key.Endpoint == "https://slow.example.com" {
+			<-release
+		}
+
+		// constructors may use the pool themselves
+		if key.Endpoint == "https://nested.example.com" {
+			if _, err := p.Get(stdsdk.PoolKey{Endpoint: "https://fast.example.com"}); err != nil {
+				return nil, err
+			}
+		}
+
+		return stdsdk.New(key.Endpoint)
+	}
+
+	slow := stdsdk.PoolKey{Endpoint: "https://slow.example.com"}
+
+	var wg sync.WaitGroup
+	clients := make([]*stdsdk.Client, 3)
+
+	for i := range clients {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			c, err := p.Get(slow)
+			if err != nil {
+				t.Error(err)
+			}
+			clients[i] = c
+		}(i)
+	}
+
+	done := make(chan error)
+
+	go func() {
+		_, err := p.Get(stdsdk.PoolKey{Endpoint: "https://nested.example.com"})
+		done <- err
+	}()
+
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Fatal(err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("get blocked behind a slow constructor for another key")
+	}
+
+	close(release)
+	wg.Wait()
+
+	if clients[0] == nil || clients[0] != clients[1] || clients[1] != clients[2] {
+		t.Error("concurrent gets for one key returned different clients")
+	}
+
+	// slow once, nested and the fast client it requested
+	if n := atomic.LoadInt32(&builds); n != 3 {
+		t.Errorf("builds = %d, want 3", n)
+	}
+}
@@ -0,0 +1,74 @@
+package stdsdk
+
+import (
+	"context"
+	"time"
+)
+
+type Clock interface {
+	Now() time.Time
+	Sleep(ctx context.Context, d time.Duration) error
+}
+
+var SystemClock Clock = systemClock{}
+
+type systemClock struct{}
+
+func (systemClock) Now() time.Time {
+	return time.Now()
+}
+
+func (systemClock) Sleep(ctx context.Context, d time.Duration) error {
+	t := time.NewTimer(d)
+	defer t.Stop()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-t.C:
+		return nil
+	}
+}
+
+func (c *Client) clock() Clock {
+	if c.Clock == nil {
+		return SystemClock
+	}
+
+	return c.Clock
+}
+
+type clockKey struct{}
+
+// withClock passes clock to auth and stores that have no clock of their own
+func withClock(ctx context.Context, clock Clock) context.Context {
+	return context.WithValue(ctx, clockKey{}, clock)
+}
+
+// contextClock prefers own, then the clock carried by ctx
+func contextClock(ctx context.Context, own Clock) Clock {
+	fallback, _ := ctx.Value(clockKey{}).(Clock)
+	return orClock(own, fallback)
+}
+
+// localClock is contextClock without server skew correction, expiry of
+// cached values is computed and checked in local time
+func localClock(ctx context.Context, own Clock) Clock {
+	fallback, _ := ctx.Value(clockKey{}).(Clock)
+	if sc, ok := fallback.(skewedClock); ok {
+		fallback = sc.base
+	}
+	return orClock(own, fallback)
+}
+
+func orClock(own, fallback Clock) Clock {
+	if own != nil {
+		return own
+	}
+
+	if fallback != nil {
+		return fallback
+	}
+
+	return SystemClock
+}
@@ -0,0 +1,49 @@
+package stdsdk
+
+import (
+	"time"
+)
+
+// Option customizes a client derived with Clone
+type Option func(c *Client)
+
+func WithAuth(a Auth) Option {
+	return func(c *Client) {
+		c.Auth = a
+	}
+}
+
+func WithHeader(k, v string) Option {
+	return func(c *Client) {
+		c.DefaultHeaders[k] = v
+	}
+}
+
+func WithTimeout(d time.Duration) Option {
+	return func(c *Client) {
+		c.Timeout = d
+	}
+}
+
+// Clone derives a client that shares the transport, stats and caches of c
+// but has its own defaults so overrides never leak back into c
+func (c *Client) Clone(opts ...Option) *Client {
+	d := *c
+
+	d.DefaultHeaders = Headers{}
+	for k, v := range c.DefaultHeaders {
+		d.DefaultHeaders[k] = v
+	}
+
+	d.DefaultQuery = Query(copyValues(c.DefaultQuery))
+
+	if c.Prepare != nil {
+		d.Prepare = append([]PrepareFunc{}, c.Prepare...)
+	}
+
+	for _, opt := range opts {
+		opt(&d)
+	}
+
+	return &d
+}
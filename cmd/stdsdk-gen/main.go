@@ -0,0 +1,645 @@
+// stdsdk-gen emits a typed client over stdsdk.Client from an OpenAPI 3 json
+// document, typically from a go:generate directive:
+//
+//	//go:generate go run github.com/liamdawson/stdsdk/cmd/stdsdk-gen -spec api.json -package api -o api_gen.go
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"go/format"
+	"io/ioutil"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+func main() {
+	spec := flag.String("spec", "", "openapi json document")
+	pkg := flag.String("package", "api", "package name of the generated file")
+	out := flag.String("o", "", "output file (default stdout)")
+
+	flag.Parse()
+
+	if err := run(*spec, *pkg, *out); err != nil {
+		fmt.Fprintf(os.Stderr, "stdsdk-gen: %s\n", err)
+		os.Exit(1)
+	}
+}
+
+func run(spec, pkg, out string) error {
+	if spec == "" {
+		return fmt.Errorf("-spec is required")
+	}
+
+	data, err := ioutil.ReadFile(spec)
+	if err != nil {
+		return err
+	}
+
+	src, err := generate(data, pkg)
+	if err != nil {
+		return err
+	}
+
+	if out == "" {
+		_, err := os.Stdout.Write(src)
+		return err
+	}
+
+	return ioutil.WriteFile(out, src, 0644)
+}
+
+func generate(data []byte, pkg string) ([]byte, error) {
+	var doc map[string]interface{}
+
+	if err := json.Unmarshal(data, &doc); err != nil {
+		return nil, fmt.Errorf("invalid openapi document: %w", err)
+	}
+
+	g := &generator{doc: doc, types: map[string]bool{}}
+
+	return g.generate(pkg)
+}
+
+type generator struct {
+	body    bytes.Buffer
+	decls   bytes.Buffer
+	doc     map[string]interface{}
+	errors  map[int]string
+	imports map[string]bool
+	types   map[string]bool
+}
+
+type operation struct {
+	method string
+	name   string
+	op     map[string]interface{}
+	params []interface{}
+	path   string
+}
+
+func (g *generator) generate(pkg string) ([]byte, error) {
+	g.errors = map[int]string{}
+	g.imports = map[string]bool{"github.com/liamdawson/stdsdk": true}
+
+	schemas := object(object(g.doc["components"])["schemas"])
+
+	for _, name := range sortedKeys(schemas) {
+		g.namedType(exported(name), schemas[name])
+	}
+
+	ops, err := g.operations()
+	if err != nil {
+		return nil, err
+	}
+
+	for _, op := range ops {
+		g.operation(op)
+	}
+
+	g.errorDecoder()
+
+	var src bytes.Buffer
+
+	fmt.Fprintf(&src, "// Code generated by stdsdk-gen. DO NOT EDIT.\n\npackage %s\n\nimport (\n", pkg)
+
+	imports := []string{}
+	for imp := range g.imports {
+		imports = append(imports, imp)
+	}
+	sort.Strings(imports)
+
+	for _, imp := range imports {
+		if !strings.Contains(imp, ".") {
+			fmt.Fprintf(&src, "\t%q\n", imp)
+		}
+	}
+
+	src.WriteString("\n")
+
+	for _, imp := range imports {
+		if strings.Contains(imp, ".") {
+			fmt.Fprintf(&src, "\t%q\n", imp)
+		}
+	}
+
+	src.WriteString(")\n\n")
+
+	src.WriteString(`type Client struct {
+	sdk *stdsdk.Client
+}
+
+// New wraps a copy of c so the generated error mapping does not leak into
+// other users of the same client
+func New(c *stdsdk.Client) *Client {
+	cc := *c
+	cc.ErrorDecoder = decodeError
+	return &Client{sdk: &cc}
+}
+
+func (c *Client) SDK() *stdsdk.Client {
+	return c.sdk
+}
+
+`)
+
+	src.Write(g.decls.Bytes())
+	src.Write(g.body.Bytes())
+
+	formatted, err := format.Source(src.Bytes())
+	if err != nil {
+		return nil, fmt.Errorf("could not format generated code: %w", err)
+	}
+
+	return formatted, nil
+}
+
+func (g *generator) operations() ([]operation, error) {
+	paths := object(g.doc["paths"])
+	if paths == nil {
+		return nil, fmt.Errorf("invalid openapi document: missing paths")
+	}
+
+	ops := []operation{}
+	names := map[string]string{}
+
+	for _, path := range sortedKeys(paths) {
+		item := object(paths[path])
+		shared, _ := item["parameters"].([]interface{})
+
+		for _, method := range []string{"get", "put", "post", "delete", "options", "head", "patch"} {
+			op := object(item[method])
+			if op == nil {
+				continue
+			}
+
+			name, _ := op["operationId"].(string)
+			if name == "" {
+				name = method + " " + path
+			}
+			name = exported(name)
+
+			if prev, ok := names[name]; ok {
+				return nil, fmt.Errorf("duplicate operation name %s for %s and %s %s", name, prev, method, path)
+			}
+			names[name] = method + " " + path
+
+			params, _ := op["parameters"].([]interface{})
+
+			ops = append(ops, operation{
+				method: strings.ToUpper(method),
+				name:   name,
+				op:     op,
+				params: append(append([]interface{}{}, shared...), params...),
+				path:   path,
+			})
+		}
+	}
+
+	return ops, nil
+}
+
+func (g *generator) operation(o operation) {
+	opts := o.name + "Options"
+
+	// nested types are declared while fields are collected
+	var fields bytes.Buffer
+
+	seen := map[string]bool{}
+
+	for _, p := range o.params {
+		param := g.deref(p)
+
+		name, _ := param["name"].(string)
+		in, _ := param["in"].(string)
+		required, _ := param["required"].(bool)
+
+		if name == "" || seen[in+name] {
+			continue
+		}
+		seen[in+name] = true
+
+		// required params are values, MarshalOptions only checks required on
+		// pointers so the tag is left off
+		switch in {
+		case "path", "query", "header":
+		default:
+			continue
+		}
+
+		tag := fmt.Sprintf(`%s:"%s"`, in, name)
+
+		typ := g.goType(param["schema"], o.name+exported(name))
+
+		// times default to the sortable format, apis expect rfc 3339
+		if typ == "time.Time" {
+			tag += ` format:"rfc3339"`
+		}
+
+		// optional params are pointers so unset ones are left out of the request
+		if in != "path" && !required {
+			typ = nillable(typ)
+		}
+
+		fmt.Fprintf(&fields, "\t%s %s `%s`\n", exported(name), typ, tag)
+	}
+
+	body := ""
+
+	if rb := g.deref(o.op["requestBody"]); rb != nil {
+		if s, ok := jsonSchema(rb); ok {
+			body = g.goType(s, o.name+"Body")
+			fmt.Fprintf(&fields, "\tBody %s\n", body)
+		}
+	}
+
+	fmt.Fprintf(&g.decls, "type %s struct {\n%s}\n\n", opts, fields.String())
+
+	result := ""
+
+	responses := object(o.op["responses"])
+
+	for _, code := range sortedKeys(responses) {
+		status, err := strconv.Atoi(code)
+
+		res := g.deref(responses[code])
+
+		s, ok := jsonSchema(res)
+		if !ok {
+			continue
+		}
+
+		switch {
+		case result == "" && (code == "2XX" || (err == nil && status >= 200 && status < 300)):
+			result = g.goType(s, o.name+"Response")
+		case err == nil && status >= 400:
+			if _, ok := g.errors[status]; !ok {
+				g.errors[status] = g.goType(s, fmt.Sprintf("%sError%d", o.name, status))
+			}
+		}
+	}
+
+	if summary, _ := o.op["summary"].(string); summary != "" {
+		fmt.Fprintf(&g.body, "// %s %s\n", o.name, lowerFirst(strings.TrimSuffix(summary, ".")))
+	}
+
+	// slices and maps are returned by value
+	ret, ref := "*"+result, "&out"
+	if strings.HasPrefix(result, "[]") || strings.HasPrefix(result, "map[") {
+		ret, ref = result, "out"
+	}
+
+	if result != "" {
+		fmt.Fprintf(&g.body, "func (c *Client) %s(opts %s) (%s, error) {\n", o.name, opts, ret)
+	} else {
+		fmt.Fprintf(&g.body, "func (c *Client) %s(opts %s) error {\n", o.name, opts)
+	}
+
+	fail := "return err"
+	if result != "" {
+		fail = "return nil, err"
+	}
+
+	fmt.Fprintf(&g.body, "\tro, err := stdsdk.MarshalOptions(opts)\n\tif err != nil {\n\t\t%s\n\t}\n\n", fail)
+
+	if body != "" {
+		g.imports["bytes"] = true
+		fmt.Fprintf(&g.body, "\tdata, err := stdsdk.JSON.Marshal(opts.Body)\n\tif err != nil {\n\t\t%s\n\t}\n\n", fail)
+		g.body.WriteString("\tro.Body = bytes.NewReader(data)\n\tro.Headers[\"Content-Type\"] = \"application/json\"\n\n")
+	}
+
+	out := "nil"
+
+	if result != "" {
+		fmt.Fprintf(&g.body, "\tvar out %s\n\n", result)
+		out = "&out"
+	}
+
+	if m, ok := clientMethods[o.method]; ok {
+		fmt.Fprintf(&g.body, "\tif err := c.sdk.%s(%q, ro, %s); err != nil {\n\t\t%s\n\t}\n\n", m, o.path, out, fail)
+	} else {
+		fmt.Fprintf(&g.body, "\treq, err := c.sdk.Request(%q, %q, ro)\n\tif err != nil {\n\t\t%s\n\t}\n\n", o.method, o.path, fail)
+		fmt.Fprintf(&g.body, "\tres, err := c.sdk.HandleRequest(req)\n\tif err != nil {\n\t\t%s\n\t}\n\tdefer res.Body.Close()\n\n", fail)
+		if result != "" {
+			g.imports["io/ioutil"] = true
+			fmt.Fprintf(&g.body, "\traw, err := ioutil.ReadAll(res.Body)\n\tif err != nil {\n\t\t%s\n\t}\n\n", fail)
+			fmt.Fprintf(&g.body, "\tif err := stdsdk.JSON.Unmarshal(raw, &out); err != nil {\n\t\t%s\n\t}\n\n", fail)
+		}
+	}
+
+	if result != "" {
+		fmt.Fprintf(&g.body, "\treturn %s, nil\n}\n\n", ref)
+	} else {
+		g.body.WriteString("\treturn nil\n}\n\n")
+	}
+}
+
+// errorDecoder maps documented error statuses to their response types, the
+// first operation to document a status decides its type
+func (g *generator) errorDecoder() {
+	g.imports["fmt"] = true
+	g.imports["net/http"] = true
+
+	g.decls.WriteString(`type Error struct {
+	Body   []byte
+	Status int
+	Value  interface{}
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("api error %d: %s", e.Status, e.Body)
+}
+
+`)
+
+	g.body.WriteString("func decodeError(res *http.Response) error {\n\tvar v interface{}\n\n\tswitch res.StatusCode {\n")
+
+	codes := []int{}
+	for code := range g.errors {
+		codes = append(codes, code)
+	}
+	sort.Ints(codes)
+
+	for _, code := range codes {
+		fmt.Fprintf(&g.body, "\tcase %d:\n\t\tv = new(%s)\n", code, g.errors[code])
+	}
+
+	g.body.WriteString("\tdefault:\n\t\treturn nil\n\t}\n\n")
+
+	if len(codes) > 0 {
+		g.imports["io/ioutil"] = true
+		g.body.WriteString(`	data, err := ioutil.ReadAll(res.Body)
+	if err != nil {
+		return err
+	}
+
+	if err := stdsdk.JSON.Unmarshal(data, v); err != nil {
+		v = nil
+	}
+
+	return &Error{Body: data, Status: res.StatusCode, Value: v}
+`)
+	}
+
+	g.body.WriteString("}\n")
+}
+
+func (g *generator) namedType(name string, node interface{}) string {
+	s := g.deref(node)
+
+	if g.types[name] {
+		return name
+	}
+	g.types[name] = true
+
+	if s["type"] == "object" || s["properties"] != nil || s["allOf"] != nil {
+		if props, required := g.properties(s); props != nil || s["additionalProperties"] == nil {
+			g.structType(name, props, required)
+			return name
+		}
+	}
+
+	fmt.Fprintf(&g.decls, "type %s %s\n\n", name, g.goType(s, name+"Value"))
+
+	return name
+}
+
+func (g *generator) structType(name string, props map[string]interface{}, required map[string]bool) {
+	var fields bytes.Buffer
+
+	for _, p := range sortedKeys(props) {
+		typ := g.goType(props[p], name+exported(p))
+
+		// pointers break recursive types and let optional fields be omitted
+		if ps := object(props[p]); !required[p] || ps["$ref"] != nil || ps["properties"] != nil || ps["allOf"] != nil {
+			typ = nillable(typ)
+		}
+
+		tag := p
+		if !required[p] {
+			tag += ",omitempty"
+		}
+
+		fmt.Fprintf(&fields, "\t%s %s `json:\"%s\"`\n", exported(p), typ, tag)
+	}
+
+	fmt.Fprintf(&g.decls, "type %s struct {\n%s}\n\n", name, fields.String())
+}
+
+// properties flattens allOf into a single property set
+func (g *generator) properties(s map[string]interface{}) (map[string]interface{}, map[string]bool) {
+	props := map[string]interface{}{}
+	required := map[string]bool{}
+
+	if all, ok := s["allOf"].([]interface{}); ok {
+		for _, part := range all {
+			pp, rr := g.properties(g.deref(part))
+			for k, v := range pp {
+				props[k] = v
+			}
+			for k := range rr {
+				required[k] = true
+			}
+		}
+	}
+
+	for k, v := range object(s["properties"]) {
+		props[k] = v
+	}
+
+	if req, ok := s["required"].([]interface{}); ok {
+		for _, r := range req {
+			if name, ok := r.(string); ok {
+				required[name] = true
+			}
+		}
+	}
+
+	if len(props) == 0 {
+		return nil, required
+	}
+
+	return props, required
+}
+
+func (g *generator) goType(node interface{}, hint string) string {
+	s := object(node)
+	if s == nil {
+		return "interface{}"
+	}
+
+	if ref, ok := s["$ref"].(string); ok {
+		name := exported(ref[strings.LastIndex(ref, "/")+1:])
+		return g.namedType(name, node)
+	}
+
+	switch s["type"] {
+	case "string":
+		if s["format"] == "date-time" {
+			g.imports["time"] = true
+			return "time.Time"
+		}
+		return "string"
+	case "integer":
+		if s["format"] == "int32" {
+			return "int32"
+		}
+		return "int64"
+	case "number":
+		if s["format"] == "float" {
+			return "float32"
+		}
+		return "float64"
+	case "boolean":
+		return "bool"
+	case "array":
+		return "[]" + g.goType(s["items"], hint+"Item")
+	}
+
+	if s["properties"] != nil || s["allOf"] != nil {
+		return g.namedType(hint, node)
+	}
+
+	if ap, ok := s["additionalProperties"].(map[string]interface{}); ok {
+		return "map[string]" + g.goType(ap, hint+"Value")
+	}
+
+	if s["type"] == "object" {
+		return "map[string]interface{}"
+	}
+
+	return "interface{}"
+}
+
+// nillable returns a type that can be nil, slices and maps already are
+func nillable(typ string) string {
+	if strings.HasPrefix(typ, "[]") || strings.HasPrefix(typ, "map[") || typ == "interface{}" {
+		return typ
+	}
+
+	return "*" + typ
+}
+
+func (g *generator) deref(node interface{}) map[string]interface{} {
+	m := object(node)
+
+	for i := 0; m != nil && i < 16; i++ {
+		ref, ok := m["$ref"].(string)
+		if !ok || !strings.HasPrefix(ref, "#/") {
+			break
+		}
+
+		var cur interface{} = g.doc
+
+		for _, part := range strings.Split(ref[2:], "/") {
+			cur = object(cur)[strings.NewReplacer("~1", "/", "~0", "~").Replace(part)]
+		}
+
+		m = object(cur)
+	}
+
+	return m
+}
+
+func jsonSchema(node map[string]interface{}) (interface{}, bool) {
+	content := object(node["content"])
+
+	for _, ct := range sortedKeys(content) {
+		if strings.HasSuffix(ct, "json") {
+			s, ok := object(content[ct])["schema"]
+			return s, ok
+		}
+	}
+
+	return nil, false
+}
+
+func object(v interface{}) map[string]interface{} {
+	m, _ := v.(map[string]interface{})
+	return m
+}
+
+func sortedKeys(m map[string]interface{}) []string {
+	keys := make([]string, 0, len(m))
+
+	for k := range m {
+		keys = append(keys, k)
+	}
+
+	sort.Strings(keys)
+
+	return keys
+}
+
+// methods without a matching client helper go through Request
+var clientMethods = map[string]string{
+	"DELETE": "Delete", "GET": "Get", "OPTIONS": "Options", "POST": "Post", "PUT": "Put",
+}
+
+var initialisms = map[string]string{
+	"api": "API", "http": "HTTP", "id": "ID", "json": "JSON", "uri": "URI", "url": "URL", "uuid": "UUID",
+}
+
+// exported turns names like get_user-by id or getUserById into GetUserByID
+func exported(s string) string {
+	var words []string
+	var cur []rune
+
+	flush := func() {
+		if len(cur) > 0 {
+			words = append(words, string(cur))
+			cur = nil
+		}
+	}
+
+	for i, r := range s {
+		switch {
+		case !unicode.IsLetter(r) && !unicode.IsDigit(r):
+			flush()
+		case unicode.IsUpper(r) && i > 0 && len(cur) > 0 && unicode.IsLower(cur[len(cur)-1]):
+			flush()
+			cur = append(cur, r)
+		default:
+			cur = append(cur, r)
+		}
+	}
+
+	flush()
+
+	var b strings.Builder
+
+	for _, w := range words {
+		if i, ok := initialisms[strings.ToLower(w)]; ok {
+			b.WriteString(i)
+			continue
+		}
+
+		rs := []rune(strings.ToLower(w))
+		rs[0] = unicode.ToUpper(rs[0])
+		b.WriteString(string(rs))
+	}
+
+	name := b.String()
+
+	if name == "" || unicode.IsDigit(rune(name[0])) {
+		name = "X" + name
+	}
+
+	return name
+}
+
+func lowerFirst(s string) string {
+	if s == "" {
+		return s
+	}
+
+	rs := []rune(s)
+	rs[0] = unicode.ToLower(rs[0])
+
+	return string(rs)
+}
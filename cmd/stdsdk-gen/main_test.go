@@ -0,0 +1,66 @@
+package main
+
+import (
+	"bytes"
+	"flag"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+var update = flag.Bool("update", false, "rewrite golden files")
+
+func TestGenerateGolden(t *testing.T) {
+	specs, err := filepath.Glob("testdata/*.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, spec := range specs {
+		golden := strings.TrimSuffix(spec, ".json") + ".golden"
+
+		t.Run(filepath.Base(spec), func(t *testing.T) {
+			data, err := ioutil.ReadFile(spec)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			src, err := generate(data, "api")
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if *update {
+				if err := ioutil.WriteFile(golden, src, 0644); err != nil {
+					t.Fatal(err)
+				}
+				return
+			}
+
+			want, err := ioutil.ReadFile(golden)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if !bytes.Equal(src, want) {
+				t.Errorf("generated code does not match %s, run go test -update to accept:\n%s", golden, src)
+			}
+		})
+	}
+}
+
+func TestExported(t *testing.T) {
+	tests := map[string]string{
+		"get_user-by id": "GetUserByID",
+		"getUserById":    "GetUserByID",
+		"2fa":            "X2fa",
+		"api url":        "APIURL",
+	}
+
+	for in, want := range tests {
+		if got := exported(in); got != want {
+			t.Errorf("exported(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
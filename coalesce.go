@@ -0,0 +1,130 @@
+package stdsdk
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"io/ioutil"
+	"net/http"
+	"sort"
+	"sync"
+)
+
+// flightsLock guards the lazy group of clients not built with New
+var flightsLock sync.Mutex
+
+type flightGroup struct {
+	lock  sync.Mutex
+	calls map[string]*flight
+}
+
+type flight struct {
+	body     []byte
+	canceled bool
+	done     chan struct{}
+	err      error
+	res      *http.Response
+}
+
+func (c *Client) flightGroup() *flightGroup {
+	flightsLock.Lock()
+	defer flightsLock.Unlock()
+
+	if c.flights == nil {
+		c.flights = &flightGroup{}
+	}
+
+	return c.flights
+}
+
+// coalesced sends req unless an identical request is already in flight, in
+// which case it waits for and shares that response. Followers retry when the
+// leader's own context ended the call since theirs may still be live
+func (c *Client) coalesced(req *http.Request) (*http.Response, error) {
+	g := c.flightGroup()
+
+	// the leader reads the whole body here so the timeout can end with the call
+	req, cancel := withTimeout(req)
+	if cancel != nil {
+		defer cancel()
+	}
+
+	key := flightKey(req)
+
+	g.lock.Lock()
+
+	if f, ok := g.calls[key]; ok {
+		g.lock.Unlock()
+
+		select {
+		case <-f.done:
+			if f.canceled && req.Context().Err() == nil {
+				return c.coalesced(req)
+			}
+			return f.response()
+		case <-req.Context().Done():
+			return nil, req.Context().Err()
+		}
+	}
+
+	f := &flight{done: make(chan struct{})}
+
+	if g.calls == nil {
+		g.calls = map[string]*flight{}
+	}
+
+	g.calls[key] = f
+
+	g.lock.Unlock()
+
+	res, err := c.HandleRequest(req)
+	if err == nil {
+		f.body, err = ioutil.ReadAll(res.Body)
+		res.Body.Close()
+	}
+
+	f.res, f.err = res, err
+	f.canceled = err != nil && req.Context().Err() != nil
+
+	g.lock.Lock()
+	delete(g.calls, key)
+	g.lock.Unlock()
+
+	close(f.done)
+
+	return f.response()
+}
+
+func (f *flight) response() (*http.Response, error) {
+	if f.err != nil {
+		return nil, f.err
+	}
+
+	r := *f.res
+	r.Header = f.res.Header.Clone()
+	r.Body = ioutil.NopCloser(bytes.NewReader(f.body))
+
+	return &r, nil
+}
+
+func flightKey(req *http.Request) string {
+	h := sha256.New()
+
+	h.Write([]byte(req.Method + " " + req.URL.String() + "\n"))
+
+	keys := make([]string, 0, len(req.Header))
+
+	for k := range req.Header {
+		keys = append(keys, k)
+	}
+
+	sort.Strings(keys)
+
+	for _, k := range keys {
+		for _, v := range req.Header[k] {
+			h.Write([]byte(k + ": " + v + "\n"))
+		}
+	}
+
+	return hex.EncodeToString(h.Sum(nil))
+}
@@ -0,0 +1,79 @@
+package stdsdk_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/liamdawson/stdsdk"
+)
+
+func TestCoalesceLeaderTimeout(t *testing.T) {
+	var hits int32
+
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&hits, 1)
+		time.Sleep(100 * time.Millisecond)
+		w.Write([]byte("ok"))
+	}))
+	defer ts.Close()
+
+	u, err := url.Parse(ts.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// a literal client has no flight group until the first coalesced call
+	c := (&stdsdk.Client{Coalesce: true, Endpoint: u}).WithContext(context.Background())
+
+	leader := make(chan error)
+
+	go func() {
+		leader <- c.Get("/", stdsdk.RequestOptions{Timeout: 20 * time.Millisecond}, nil)
+	}()
+
+	time.Sleep(5 * time.Millisecond)
+
+	var s string
+
+	if err := c.Get("/", stdsdk.RequestOptions{Timeout: time.Second}, &s); err != nil {
+		t.Fatalf("follower: %v", err)
+	}
+
+	if s != "ok" {
+		t.Errorf("follower body = %q", s)
+	}
+
+	if err := <-leader; err == nil {
+		t.Error("leader: expected timeout")
+	}
+
+	if n := atomic.LoadInt32(&hits); n != 2 {
+		t.Errorf("hits = %d, want 2", n)
+	}
+
+	// with both contexts live the second call shares the first response
+	atomic.StoreInt32(&hits, 0)
+
+	go func() {
+		leader <- c.Get("/", stdsdk.RequestOptions{}, nil)
+	}()
+
+	time.Sleep(5 * time.Millisecond)
+
+	if err := c.Get("/", stdsdk.RequestOptions{}, nil); err != nil {
+		t.Fatalf("follower: %v", err)
+	}
+
+	if err := <-leader; err != nil {
+		t.Fatalf("leader: %v", err)
+	}
+
+	if n := atomic.LoadInt32(&hits); n != 1 {
+		t.Errorf("hits = %d, want 1", n)
+	}
+}
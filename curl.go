@@ -0,0 +1,79 @@
+package stdsdk
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"sort"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Curl renders req as a curl command, a body that is not text cannot be
+// passed on the command line so it is left out and noted in a comment
+func Curl(req *http.Request) (string, error) {
+	parts := []string{"curl", "-X", shellQuote(req.Method)}
+
+	// -X HEAD makes curl wait for a body that never comes
+	if req.Method == "HEAD" {
+		parts = []string{"curl", "-I"}
+	}
+
+	var note string
+
+	keys := make([]string, 0, len(req.Header))
+
+	for k := range req.Header {
+		keys = append(keys, k)
+	}
+
+	sort.Strings(keys)
+
+	for _, k := range keys {
+		for _, v := range req.Header[k] {
+			parts = append(parts, "-H", shellQuote(fmt.Sprintf("%s: %s", k, v)))
+		}
+	}
+
+	if req.Body != nil {
+		data, err := ioutil.ReadAll(req.Body)
+		if err != nil {
+			return "", err
+		}
+
+		req.Body.Close()
+		req.Body = ioutil.NopCloser(bytes.NewReader(data))
+
+		switch {
+		case len(data) == 0:
+		case binary(data):
+			note = fmt.Sprintf(" # binary body of %d bytes omitted", len(data))
+		default:
+			parts = append(parts, "--data-binary", shellQuote(string(data)))
+		}
+	}
+
+	parts = append(parts, shellQuote(req.URL.String()))
+
+	return strings.Join(parts, " ") + note, nil
+}
+
+func binary(data []byte) bool {
+	if !utf8.Valid(data) {
+		return true
+	}
+
+	for _, r := range string(data) {
+		if unicode.IsControl(r) && !unicode.IsSpace(r) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func shellQuote(s string) string {
+	return "'" + strings.Replace(s, "'", `'\''`, -1) + "'"
+}
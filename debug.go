@@ -0,0 +1,213 @@
+package stdsdk
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"sort"
+	"strings"
+)
+
+const (
+	debugBodyLimit = 4096
+	redacted       = "[REDACTED]"
+)
+
+var sensitiveHeaders = map[string]bool{
+	"Authorization":       true,
+	"Cookie":              true,
+	"Proxy-Authorization": true,
+	"Set-Cookie":          true,
+	"X-Api-Key":           true,
+	"X-Signature":         true,
+}
+
+type Debug struct {
+	Bodies    bool
+	BodyLimit int
+	Writer    io.Writer
+}
+
+func debugFromEnv() *Debug {
+	switch v := os.Getenv("STDSDK_DEBUG"); v {
+	case "", "0", "false":
+		return nil
+	default:
+		return &Debug{Bodies: v == "body", Writer: os.Stderr}
+	}
+}
+
+func (d *Debug) limit() int {
+	if d.BodyLimit > 0 {
+		return d.BodyLimit
+	}
+
+	return debugBodyLimit
+}
+
+func (d *Debug) request(req *http.Request) {
+	var buf bytes.Buffer
+
+	fmt.Fprintf(&buf, "> %s %s %s\n", req.Method, redactRequestURL(req), req.Proto)
+	fmt.Fprintf(&buf, "> Host: %s\n", req.URL.Host)
+
+	writeDebugHeaders(&buf, ">", req.Header, sensitiveFor(req))
+
+	if d.Bodies && req.Body != nil {
+		body, rc := d.peek(req.Body)
+		req.Body = rc
+		writeDebugBody(&buf, ">", body)
+	}
+
+	d.writer().Write(buf.Bytes())
+}
+
+func (d *Debug) response(res *http.Response) {
+	var buf bytes.Buffer
+
+	fmt.Fprintf(&buf, "< %s %s\n", res.Proto, res.Status)
+
+	writeDebugHeaders(&buf, "<", res.Header, sensitiveFor(res.Request))
+
+	if d.Bodies && res.Body != nil {
+		body, rc := d.peek(res.Body)
+		res.Body = rc
+		writeDebugBody(&buf, "<", body)
+	}
+
+	d.writer().Write(buf.Bytes())
+}
+
+func (d *Debug) writer() io.Writer {
+	if d.Writer != nil {
+		return d.Writer
+	}
+
+	return os.Stderr
+}
+
+// peek reads up to the body limit and returns a reader that replays it
+// ahead of the remaining unread body
+func (d *Debug) peek(rc io.ReadCloser) ([]byte, io.ReadCloser) {
+	data, _ := ioutil.ReadAll(io.LimitReader(rc, int64(d.limit())))
+
+	return data, readCloser{io.MultiReader(bytes.NewReader(data), rc), rc}
+}
+
+type readCloser struct {
+	io.Reader
+	io.Closer
+}
+
+func writeDebugHeaders(w io.Writer, prefix string, h http.Header, sensitive func(string) bool) {
+	keys := make([]string, 0, len(h))
+
+	for k := range h {
+		keys = append(keys, k)
+	}
+
+	sort.Strings(keys)
+
+	for _, k := range keys {
+		for _, v := range h[k] {
+			if sensitive(k) {
+				v = redacted
+			}
+			fmt.Fprintf(w, "%s %s: %s\n", prefix, k, v)
+		}
+	}
+}
+
+func writeDebugBody(w io.Writer, prefix string, body []byte) {
+	fmt.Fprintf(w, "%s\n", prefix)
+
+	for _, line := range strings.Split(strings.TrimRight(Redact(string(body)), "\n"), "\n") {
+		fmt.Fprintf(w, "%s %s\n", prefix, line)
+	}
+}
+
+// redactURL masks userinfo and the values of credential looking query params
+func redactURL(s string) string {
+	if i := strings.Index(s, "://"); i >= 0 {
+		j := strings.Index(s[i+3:], "@")
+		if j >= 0 && !strings.Contains(s[i+3:i+3+j], "/") {
+			s = s[:i+3] + redacted + s[i+3+j:]
+		}
+	}
+
+	q := strings.Index(s, "?")
+	if q < 0 {
+		return s
+	}
+
+	frag := ""
+	if f := strings.Index(s[q:], "#"); f >= 0 {
+		s, frag = s[:q+f], s[q+f:]
+	}
+
+	pairs := strings.Split(s[q+1:], "&")
+
+	for i, p := range pairs {
+		k := p
+		if e := strings.Index(p, "="); e >= 0 {
+			k = p[:e]
+		}
+		if name, err := url.QueryUnescape(k); err == nil && sensitiveQuery(name) {
+			pairs[i] = k + "=" + redacted
+		}
+	}
+
+	return s[:q+1] + strings.Join(pairs, "&") + frag
+}
+
+var sensitiveQueryNames = map[string]bool{
+	"auth":      true,
+	"code":      true,
+	"key":       true,
+	"passwd":    true,
+	"password":  true,
+	"pwd":       true,
+	"secret":    true,
+	"sig":       true,
+	"signature": true,
+	"token":     true,
+}
+
+// credentialQuery is the narrower set of names that carry credentials, unlike
+// sensitiveQuery it leaves functional params such as page_token or sort_key
+func credentialQuery(name string) bool {
+	n := strings.ToLower(strings.NewReplacer("-", "", "_", "", ".", "").Replace(name))
+
+	if sensitiveQueryNames[n] {
+		return true
+	}
+
+	for _, suffix := range []string{"accesstoken", "apikey", "idtoken", "password", "refreshtoken", "secret", "signature"} {
+		if strings.HasSuffix(n, suffix) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// sensitiveQuery matches names like key, api_key, access-token or clientSecret
+func sensitiveQuery(name string) bool {
+	n := strings.ToLower(strings.NewReplacer("-", "", "_", "", ".", "").Replace(name))
+
+	if sensitiveQueryNames[n] {
+		return true
+	}
+
+	for _, suffix := range []string{"key", "password", "secret", "signature", "token"} {
+		if strings.HasSuffix(n, suffix) {
+			return true
+		}
+	}
+
+	return false
+}
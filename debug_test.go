@@ -0,0 +1,67 @@
+package stdsdk_test
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/liamdawson/stdsdk"
+)
+
+func TestDebugRedaction(t *testing.T) {
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"access_token":"tok-response"}`))
+	}))
+	defer s.Close()
+
+	c, err := stdsdk.New(s.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var buf bytes.Buffer
+
+	c.Auth = stdsdk.APIKey{Key: "query-secret", Name: "k", Placement: stdsdk.APIKeyQuery}
+	c.Debug = &stdsdk.Debug{Bodies: true, Writer: &buf}
+	c.DefaultHeaders = stdsdk.Headers{"X-Tenant-Token": "header-secret"}
+	c.SensitiveHeaders = []string{"x-tenant-token"}
+
+	opts := stdsdk.RequestOptions{
+		Params: stdsdk.Params{"password": "body-secret"},
+		Query:  stdsdk.Query{"api_key": "named-secret", "page": "2"},
+	}
+
+	if err := c.Post("/login", opts, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	out := buf.String()
+
+	for _, secret := range []string{"query-secret", "header-secret", "body-secret", "named-secret", "tok-response"} {
+		if strings.Contains(out, secret) {
+			t.Errorf("debug output contains %s:\n%s", secret, out)
+		}
+	}
+
+	if !strings.Contains(out, "page=2") {
+		t.Errorf("debug output lost unrelated query params:\n%s", out)
+	}
+}
+
+func TestDebugDefaultWriter(t *testing.T) {
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer s.Close()
+
+	c, err := stdsdk.New(s.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	c.Debug = &stdsdk.Debug{}
+
+	if err := c.Get("/", stdsdk.RequestOptions{}, nil); err != nil {
+		t.Fatal(err)
+	}
+}
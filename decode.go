@@ -0,0 +1,165 @@
+package stdsdk
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+)
+
+type ResponseDecoder interface {
+	DecodeResponse(res *http.Response) error
+}
+
+type DecodeFunc func(r io.Reader) error
+
+type decoding struct {
+	envelope  string
+	useNumber bool
+}
+
+func (c *Client) decoding(opts RequestOptions) decoding {
+	d := decoding{envelope: c.Envelope, useNumber: c.UseNumber || opts.UseNumber}
+
+	if opts.Envelope != "" {
+		d.envelope = opts.Envelope
+	}
+
+	// allow a request to opt out of a client-wide envelope
+	if d.envelope == "-" {
+		d.envelope = ""
+	}
+
+	return d
+}
+
+// Decode turns an error status into a *ResponseError and otherwise decodes
+// res into out as the verb helpers do, fakes such as the mock package use it
+// to behave like a real client
+func (c *Client) Decode(res *http.Response, out interface{}, opts RequestOptions) error {
+	if err := responseError(res); err != nil {
+		res.Body.Close()
+		return err
+	}
+
+	return c.decodeResponse(res, out, opts)
+}
+
+func (c *Client) decodeResponse(res *http.Response, out interface{}, opts RequestOptions) error {
+	if err := c.decode(res, out, opts); err != nil {
+		return annotateResponse(res, err)
+	}
+
+	return nil
+}
+
+func (c *Client) decode(res *http.Response, out interface{}, opts RequestOptions) error {
+	captureResponse(res, opts)
+
+	// validating a stream would buffer it whole, so streams are left alone
+	if !streaming(out) {
+		if err := c.validateResponse(res, opts); err != nil {
+			res.Body.Close()
+			return err
+		}
+	}
+
+	switch t := out.(type) {
+	case ResponseDecoder:
+		defer res.Body.Close()
+		return t.DecodeResponse(res)
+	case DecodeFunc:
+		defer res.Body.Close()
+		return t(res.Body)
+	case func(io.Reader) error:
+		defer res.Body.Close()
+		return t(res.Body)
+	}
+
+	return unmarshalReader(res.Body, out, c.decoding(opts))
+}
+
+func streaming(out interface{}) bool {
+	switch out.(type) {
+	case ResponseDecoder, DecodeFunc, func(io.Reader) error, io.Writer:
+		return true
+	}
+
+	return false
+}
+
+type responseHeadersKey struct{}
+
+// captureResponse fills the response sinks requested in opts
+func captureResponse(res *http.Response, opts RequestOptions) {
+	if opts.ResponseHeaders != nil {
+		*opts.ResponseHeaders = res.Header
+	}
+}
+
+func unmarshalReader(r io.ReadCloser, out interface{}, d decoding) error {
+	defer r.Close()
+
+	if out == nil {
+		return nil
+	}
+
+	if w, ok := out.(io.Writer); ok {
+		_, err := copyBuffered(w, r)
+		return err
+	}
+
+	buf := getBuffer()
+	defer putBuffer(buf)
+
+	if _, err := buf.ReadFrom(r); err != nil {
+		return err
+	}
+
+	data := buf.Bytes()
+
+	switch t := out.(type) {
+	case *[]byte:
+		*t = append([]byte{}, data...)
+		return nil
+	case *string:
+		*t = string(data)
+		return nil
+	}
+
+	// the buffer goes back to the pool, only encoding/json is known to copy
+	// what it keeps so other codecs decode a private copy
+	if _, ok := JSON.(StandardJSON); !ok {
+		data = append([]byte{}, data...)
+	}
+
+	return d.unmarshal(data, out)
+}
+
+func (d decoding) unmarshal(data []byte, out interface{}) error {
+	if d.envelope != "" {
+		var env map[string]json.RawMessage
+
+		if err := JSON.Unmarshal(data, &env); err != nil {
+			return err
+		}
+
+		inner, ok := env[d.envelope]
+		if !ok {
+			return fmt.Errorf("response envelope missing key: %s", d.envelope)
+		}
+
+		data = inner
+	}
+
+	// codecs that cannot keep numbers exact fall back to encoding/json
+	if d.useNumber {
+		nd, ok := JSON.(NumberDecoder)
+		if !ok {
+			nd = StandardJSON{}
+		}
+		return nd.UnmarshalUseNumber(data, out)
+	}
+
+	return JSON.Unmarshal(data, out)
+}
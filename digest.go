@@ -0,0 +1,210 @@
+package stdsdk
+
+import (
+	"context"
+	"crypto/md5"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"hash"
+	"net/http"
+	"strings"
+	"sync"
+)
+
+type DigestAuth struct {
+	Username string
+	Password string
+
+	lock      sync.Mutex
+	challenge map[string]string
+	nc        int
+}
+
+var _ UnauthorizedHandler = &DigestAuth{}
+
+func (d *DigestAuth) credentials() []string {
+	return []string{d.Password}
+}
+
+func (d *DigestAuth) OnUnauthorized(ctx context.Context, res *http.Response) (bool, error) {
+	var challenge map[string]string
+
+	for _, v := range res.Header.Values("WWW-Authenticate") {
+		if strings.HasPrefix(strings.ToLower(v), "digest ") {
+			challenge = parseChallenge(v[len("digest "):])
+			break
+		}
+	}
+
+	if challenge == nil {
+		return false, nil
+	}
+
+	d.lock.Lock()
+	defer d.lock.Unlock()
+
+	// same nonce rejected again means bad credentials unless marked stale
+	if d.challenge != nil && d.challenge["nonce"] == challenge["nonce"] && !strings.EqualFold(challenge["stale"], "true") {
+		return false, nil
+	}
+
+	d.challenge = challenge
+	d.nc = 0
+
+	return true, nil
+}
+
+func (d *DigestAuth) Apply(ctx context.Context, req *http.Request) error {
+	d.lock.Lock()
+
+	challenge := d.challenge
+
+	if challenge == nil {
+		d.lock.Unlock()
+		return nil
+	}
+
+	d.nc++
+	nc := fmt.Sprintf("%08x", d.nc)
+
+	d.lock.Unlock()
+
+	algorithm := challenge["algorithm"]
+	if algorithm == "" {
+		algorithm = "MD5"
+	}
+
+	var hf func() hash.Hash
+
+	switch strings.TrimSuffix(strings.ToUpper(algorithm), "-SESS") {
+	case "MD5":
+		hf = md5.New
+	case "SHA-256":
+		hf = sha256.New
+	default:
+		return fmt.Errorf("unsupported digest algorithm: %s", algorithm)
+	}
+
+	h := func(s string) string {
+		x := hf()
+		x.Write([]byte(s))
+		return hex.EncodeToString(x.Sum(nil))
+	}
+
+	qop := ""
+	if q, ok := challenge["qop"]; ok {
+		for _, o := range strings.Split(q, ",") {
+			if strings.TrimSpace(o) == "auth" {
+				qop = "auth"
+			}
+		}
+		if qop == "" {
+			return fmt.Errorf("unsupported digest qop: %s", q)
+		}
+	}
+
+	nonce := challenge["nonce"]
+
+	cnonce, err := randomHex(16)
+	if err != nil {
+		return err
+	}
+
+	uri := req.URL.RequestURI()
+
+	ha1 := h(fmt.Sprintf("%s:%s:%s", d.Username, challenge["realm"], d.Password))
+
+	if strings.HasSuffix(strings.ToLower(algorithm), "-sess") {
+		ha1 = h(fmt.Sprintf("%s:%s:%s", ha1, nonce, cnonce))
+	}
+
+	ha2 := h(fmt.Sprintf("%s:%s", req.Method, uri))
+
+	var response string
+
+	if qop == "" {
+		response = h(fmt.Sprintf("%s:%s:%s", ha1, nonce, ha2))
+	} else {
+		response = h(fmt.Sprintf("%s:%s:%s:%s:%s:%s", ha1, nonce, nc, cnonce, qop, ha2))
+	}
+
+	parts := []string{
+		fmt.Sprintf("username=%q", d.Username),
+		fmt.Sprintf("realm=%q", challenge["realm"]),
+		fmt.Sprintf("nonce=%q", nonce),
+		fmt.Sprintf("uri=%q", uri),
+		fmt.Sprintf("algorithm=%s", algorithm),
+		fmt.Sprintf("response=%q", response),
+	}
+
+	if qop != "" {
+		parts = append(parts, fmt.Sprintf("qop=%s", qop), fmt.Sprintf("nc=%s", nc), fmt.Sprintf("cnonce=%q", cnonce))
+	}
+
+	if o, ok := challenge["opaque"]; ok {
+		parts = append(parts, fmt.Sprintf("opaque=%q", o))
+	}
+
+	req.Header.Set("Authorization", "Digest "+strings.Join(parts, ", "))
+
+	return nil
+}
+
+func parseChallenge(s string) map[string]string {
+	params := map[string]string{}
+
+	for len(s) > 0 {
+		s = strings.TrimLeft(s, " ,")
+
+		i := strings.Index(s, "=")
+		if i < 0 {
+			break
+		}
+
+		key := strings.ToLower(strings.TrimSpace(s[:i]))
+		s = s[i+1:]
+
+		var value string
+
+		if strings.HasPrefix(s, `"`) {
+			j := 1
+			for j < len(s) && s[j] != '"' {
+				if s[j] == '\\' {
+					j++
+				}
+				j++
+			}
+			if j > len(s) {
+				j = len(s)
+			}
+			value = strings.Replace(s[1:j], `\"`, `"`, -1)
+			if j < len(s) {
+				j++
+			}
+			s = s[j:]
+		} else {
+			j := strings.Index(s, ",")
+			if j < 0 {
+				j = len(s)
+			}
+			value = strings.TrimSpace(s[:j])
+			s = s[j:]
+		}
+
+		params[key] = value
+	}
+
+	return params
+}
+
+func randomHex(n int) (string, error) {
+	b := make([]byte, n)
+
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+
+	return hex.EncodeToString(b), nil
+}
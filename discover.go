@@ -0,0 +1,193 @@
+package stdsdk
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sync"
+	"time"
+)
+
+type Discovery struct {
+	Features map[string]bool
+	Version  string
+	Versions []string
+}
+
+type FeatureError struct {
+	Feature string
+}
+
+func (e *FeatureError) Error() string {
+	return fmt.Sprintf("feature not available on server: %s", e.Feature)
+}
+
+// discoveryCache fetches outside its lock, callers arriving meanwhile wait
+// on inflight. Failures are cached until retryAt so an unreachable server
+// is not asked again on every check
+type discoveryCache struct {
+	lock      sync.Mutex
+	discovery *Discovery
+	err       error
+	failures  uint
+	inflight  chan struct{}
+	retryAt   time.Time
+}
+
+const maxDiscoveryBackoff = 5 * time.Minute
+
+type discoveryDocument struct {
+	Features json.RawMessage `json:"features"`
+	Version  string          `json:"version"`
+	Versions []string        `json:"versions"`
+}
+
+// Discover fetches DiscoveryPath, or falls back to the X-Api-Features header
+// of an OPTIONS / request, and caches the result for HasFeature
+func (c *Client) Discover(ctx context.Context) (*Discovery, error) {
+	d, err := c.WithContext(ctx).discover()
+	if err != nil {
+		return nil, err
+	}
+
+	if c.discovery != nil {
+		c.discovery.lock.Lock()
+		c.discovery.discovery = d
+		c.discovery.err = nil
+		c.discovery.failures = 0
+		c.discovery.lock.Unlock()
+	}
+
+	return d, nil
+}
+
+func (c *Client) discover() (*Discovery, error) {
+	d := &Discovery{Features: map[string]bool{}}
+
+	if c.DiscoveryPath == "" {
+		var caps Capabilities
+
+		if err := c.Options("/", RequestOptions{}, &caps); err != nil {
+			return nil, err
+		}
+
+		for _, f := range headerList(caps.Header, "X-Api-Features") {
+			d.Features[f] = true
+		}
+
+		d.Version = caps.Header.Get("X-Api-Version")
+		d.Versions = headerList(caps.Header, "X-Api-Supported-Versions")
+
+		return d, nil
+	}
+
+	var doc discoveryDocument
+
+	if err := c.Get(c.DiscoveryPath, RequestOptions{}, &doc); err != nil {
+		return nil, err
+	}
+
+	d.Version = doc.Version
+	d.Versions = doc.Versions
+
+	// features may be a list of names or an object of name to enabled
+	if len(doc.Features) > 0 {
+		var list []string
+
+		if err := JSON.Unmarshal(doc.Features, &list); err == nil {
+			for _, f := range list {
+				d.Features[f] = true
+			}
+		} else if err := JSON.Unmarshal(doc.Features, &d.Features); err != nil {
+			return nil, fmt.Errorf("invalid discovery features: %w", err)
+		}
+	}
+
+	return d, nil
+}
+
+func (c *Client) cachedDiscovery(ctx context.Context) (*Discovery, error) {
+	dc := c.discovery
+
+	if dc == nil {
+		return c.WithContext(ctx).discover()
+	}
+
+	for {
+		dc.lock.Lock()
+
+		if dc.discovery != nil {
+			d := dc.discovery
+			dc.lock.Unlock()
+			return d, nil
+		}
+
+		if dc.err != nil && c.clock().Now().Before(dc.retryAt) {
+			err := dc.err
+			dc.lock.Unlock()
+			return nil, err
+		}
+
+		if wait := dc.inflight; wait != nil {
+			dc.lock.Unlock()
+
+			select {
+			case <-wait:
+				continue
+			case <-ctx.Done():
+				return nil, ctx.Err()
+			}
+		}
+
+		done := make(chan struct{})
+		dc.inflight = done
+		dc.lock.Unlock()
+
+		d, err := c.WithContext(ctx).discover()
+
+		dc.lock.Lock()
+
+		dc.discovery, dc.inflight = d, nil
+
+		// a caller giving up says nothing about the server
+		if err != nil && ctx.Err() == nil {
+			backoff := maxDiscoveryBackoff
+
+			if dc.failures < 9 {
+				backoff = time.Second << dc.failures
+			}
+
+			dc.err, dc.retryAt = err, c.clock().Now().Add(backoff)
+			dc.failures++
+		}
+
+		dc.lock.Unlock()
+		close(done)
+
+		return d, err
+	}
+}
+
+// HasFeature discovers capabilities on first use, a failed discovery
+// reports every feature as unavailable and is retried with backoff
+func (c *Client) HasFeature(ctx context.Context, name string) bool {
+	d, err := c.cachedDiscovery(ctx)
+	if err != nil {
+		return false
+	}
+
+	return d.Features[name]
+}
+
+func (c *Client) RequireFeature(ctx context.Context, name string) error {
+	d, err := c.cachedDiscovery(ctx)
+	if err != nil {
+		return err
+	}
+
+	if !d.Features[name] {
+		return &FeatureError{Feature: name}
+	}
+
+	return nil
+}
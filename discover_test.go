@@ -0,0 +1,74 @@
+package stdsdk_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/liamdawson/stdsdk"
+)
+
+func TestHasFeatureBackoff(t *testing.T) {
+	var hits int32
+
+	up := int32(0)
+
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&hits, 1)
+
+		if atomic.LoadInt32(&up) == 0 {
+			w.WriteHeader(500)
+			return
+		}
+
+		w.Header().Set("X-Api-Features", "search")
+	}))
+	defer ts.Close()
+
+	c, err := stdsdk.New(ts.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	clock := &fixedClock{now: time.Unix(1000, 0)}
+	c.Clock = clock
+
+	var wg sync.WaitGroup
+
+	for i := 0; i < 5; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if c.HasFeature(context.Background(), "search") {
+				t.Error("feature reported while discovery fails")
+			}
+		}()
+	}
+
+	wg.Wait()
+
+	if n := atomic.LoadInt32(&hits); n != 1 {
+		t.Errorf("hits = %d, want 1", n)
+	}
+
+	atomic.StoreInt32(&up, 1)
+
+	// still backing off
+	if c.HasFeature(context.Background(), "search") {
+		t.Error("feature reported during backoff")
+	}
+
+	clock.now = clock.now.Add(2 * time.Second)
+
+	if !c.HasFeature(context.Background(), "search") {
+		t.Error("feature missing after backoff")
+	}
+
+	if n := atomic.LoadInt32(&hits); n != 2 {
+		t.Errorf("hits = %d, want 2", n)
+	}
+}
@@ -0,0 +1,78 @@
+package stdsdk
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strconv"
+	"time"
+)
+
+// NewFromEnv configures a client from STDSDK_ENDPOINT and the optional
+// STDSDK_TOKEN, STDSDK_USERNAME, STDSDK_PASSWORD, STDSDK_USER_AGENT,
+// STDSDK_TIMEOUT, STDSDK_CONNECT_TIMEOUT, STDSDK_TLS_SERVER_NAME and
+// STDSDK_TLS_SKIP_VERIFY variables
+func NewFromEnv() (*Client, error) {
+	p := &Profile{
+		Endpoint:   os.Getenv("STDSDK_ENDPOINT"),
+		Password:   os.Getenv("STDSDK_PASSWORD"),
+		ServerName: os.Getenv("STDSDK_TLS_SERVER_NAME"),
+		Token:      os.Getenv("STDSDK_TOKEN"),
+		UserAgent:  os.Getenv("STDSDK_USER_AGENT"),
+		Username:   os.Getenv("STDSDK_USERNAME"),
+	}
+
+	if p.Endpoint == "" {
+		return nil, fmt.Errorf("STDSDK_ENDPOINT is required")
+	}
+
+	var err error
+
+	if p.Timeout, err = envDuration("STDSDK_TIMEOUT"); err != nil {
+		return nil, err
+	}
+
+	if p.ConnectTimeout, err = envDuration("STDSDK_CONNECT_TIMEOUT"); err != nil {
+		return nil, err
+	}
+
+	if v := os.Getenv("STDSDK_TLS_SKIP_VERIFY"); v != "" {
+		skip, err := strconv.ParseBool(v)
+		if err != nil {
+			return nil, fmt.Errorf("invalid STDSDK_TLS_SKIP_VERIFY: %w", err)
+		}
+		p.InsecureSkipVerify = skip
+	}
+
+	return p.Client()
+}
+
+func envDuration(name string) (time.Duration, error) {
+	v := os.Getenv(name)
+	if v == "" {
+		return 0, nil
+	}
+
+	d, err := parseDuration(v)
+	if err != nil {
+		return 0, fmt.Errorf("invalid %s: %w", name, err)
+	}
+
+	return d, nil
+}
+
+// parseDuration reads timeouts from the environment and profile files, bare
+// numbers are seconds
+func parseDuration(v string) (time.Duration, error) {
+	if n, err := strconv.Atoi(v); err == nil {
+		return time.Duration(n) * time.Second, nil
+	}
+
+	return time.ParseDuration(v)
+}
+
+type staticToken string
+
+func (t staticToken) Token(ctx context.Context) (*Token, error) {
+	return &Token{Value: string(t)}, nil
+}
@@ -0,0 +1,172 @@
+package stdsdk
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"sync"
+	"time"
+)
+
+// failover resolves hosts itself so it can skip addresses that keep failing
+// and re-resolve once one is marked down, instead of repeatedly dialing a
+// dead backend behind round-robin dns
+type failover struct {
+	clock     Clock
+	cooldown  time.Duration
+	dial      dialFunc
+	family    IPFamily
+	threshold int
+
+	lock  sync.Mutex
+	hosts map[string]*failoverHost
+}
+
+type failoverHost struct {
+	down     map[string]time.Time
+	failures map[string]int
+	ips      []net.IP
+	next     int
+}
+
+func failoverDialer(dial dialFunc, opts TransportOptions) dialFunc {
+	f := &failover{
+		clock:     orClock(opts.Clock, nil),
+		cooldown:  opts.FailoverCooldown,
+		dial:      dial,
+		family:    opts.IPFamily,
+		hosts:     map[string]*failoverHost{},
+		threshold: opts.FailoverThreshold,
+	}
+
+	if f.cooldown <= 0 {
+		f.cooldown = 30 * time.Second
+	}
+
+	return f.dialContext
+}
+
+func (f *failover) dialContext(ctx context.Context, network, addr string) (net.Conn, error) {
+	host, port, err := net.SplitHostPort(addr)
+	if err != nil {
+		return nil, err
+	}
+
+	if net.ParseIP(host) != nil {
+		return f.dial(ctx, network, addr)
+	}
+
+	ips, err := f.candidates(ctx, host)
+	if err != nil {
+		return nil, err
+	}
+
+	var last error
+
+	for _, ip := range ips {
+		conn, err := f.dial(ctx, network, net.JoinHostPort(ip.String(), port))
+		if err == nil {
+			f.succeeded(host, ip)
+			return conn, nil
+		}
+
+		if ctx.Err() != nil {
+			return nil, err
+		}
+
+		f.failed(host, ip)
+		last = err
+	}
+
+	return nil, last
+}
+
+// candidates lists healthy addresses starting at the current rotation
+func (f *failover) candidates(ctx context.Context, host string) ([]net.IP, error) {
+	f.lock.Lock()
+	h, ok := f.hosts[host]
+	if !ok {
+		h = &failoverHost{down: map[string]time.Time{}, failures: map[string]int{}}
+		f.hosts[host] = h
+	}
+	resolved := h.ips != nil
+	f.lock.Unlock()
+
+	if !resolved {
+		ipas, err := net.DefaultResolver.LookupIPAddr(ctx, host)
+		if err != nil {
+			return nil, err
+		}
+
+		ips := sortFamilies(ipas, f.family, false)
+
+		if len(ips) == 0 {
+			return nil, fmt.Errorf("no addresses for host: %s", host)
+		}
+
+		f.lock.Lock()
+		h.ips = ips
+		f.lock.Unlock()
+	}
+
+	f.lock.Lock()
+	defer f.lock.Unlock()
+
+	now := f.clock.Now()
+
+	all := []net.IP{}
+	healthy := []net.IP{}
+
+	for i := range h.ips {
+		ip := h.ips[(h.next+i)%len(h.ips)]
+
+		all = append(all, ip)
+
+		if until, ok := h.down[ip.String()]; ok && now.Before(until) {
+			continue
+		}
+
+		healthy = append(healthy, ip)
+	}
+
+	// everything is down, try them all rather than failing outright
+	if len(healthy) == 0 {
+		return all, nil
+	}
+
+	return healthy, nil
+}
+
+// failed marks ip down after threshold consecutive failures and drops the
+// resolution so the next dial picks up dns changes
+func (f *failover) failed(host string, ip net.IP) {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+
+	h, ok := f.hosts[host]
+	if !ok {
+		return
+	}
+
+	key := ip.String()
+
+	if h.failures[key]++; h.failures[key] < f.threshold {
+		return
+	}
+
+	delete(h.failures, key)
+
+	h.down[key] = f.clock.Now().Add(f.cooldown)
+	h.ips = nil
+	h.next++
+}
+
+func (f *failover) succeeded(host string, ip net.IP) {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+
+	if h, ok := f.hosts[host]; ok {
+		delete(h.failures, ip.String())
+		delete(h.down, ip.String())
+	}
+}
@@ -0,0 +1,89 @@
+package stdsdk
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"strings"
+)
+
+type GraphQLError struct {
+	Extensions map[string]interface{} `json:"extensions,omitempty"`
+	Locations  []GraphQLLocation      `json:"locations,omitempty"`
+	Message    string                 `json:"message"`
+	Path       []interface{}          `json:"path,omitempty"`
+}
+
+func (e GraphQLError) Error() string {
+	if len(e.Path) == 0 {
+		return e.Message
+	}
+
+	parts := make([]string, len(e.Path))
+
+	for i, p := range e.Path {
+		parts[i] = fmt.Sprint(p)
+	}
+
+	return fmt.Sprintf("%s: %s", strings.Join(parts, "."), e.Message)
+}
+
+type GraphQLLocation struct {
+	Column int `json:"column"`
+	Line   int `json:"line"`
+}
+
+type GraphQLErrors []GraphQLError
+
+func (e GraphQLErrors) Error() string {
+	msgs := make([]string, len(e))
+
+	for i, err := range e {
+		msgs[i] = err.Error()
+	}
+
+	return fmt.Sprintf("graphql: %s", strings.Join(msgs, "; "))
+}
+
+type graphQLRequest struct {
+	Query     string                 `json:"query"`
+	Variables map[string]interface{} `json:"variables,omitempty"`
+}
+
+type graphQLResponse struct {
+	Data   json.RawMessage `json:"data"`
+	Errors GraphQLErrors   `json:"errors"`
+}
+
+// partial data is still decoded into out when the server also returns errors
+func (c *Client) GraphQL(path, query string, variables map[string]interface{}, out interface{}) error {
+	data, err := JSON.Marshal(graphQLRequest{Query: query, Variables: variables})
+	if err != nil {
+		return err
+	}
+
+	opts := RequestOptions{
+		Body:     bytes.NewReader(data),
+		Envelope: "-",
+		Headers:  Headers{"Accept": "application/json", "Content-Type": "application/json"},
+	}
+
+	var res graphQLResponse
+
+	if err := c.Post(path, opts, &res); err != nil {
+		return err
+	}
+
+	if len(res.Data) > 0 && string(res.Data) != "null" {
+		if err := unmarshalReader(ioutil.NopCloser(bytes.NewReader(res.Data)), out, c.decoding(opts)); err != nil {
+			return err
+		}
+	}
+
+	if len(res.Errors) > 0 {
+		return res.Errors
+	}
+
+	return nil
+}
@@ -0,0 +1,290 @@
+package stdsdk
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+)
+
+type HARRecorder struct {
+	enabled bool
+	entries []harEntry
+	lock    sync.Mutex
+}
+
+type harLog struct {
+	Log struct {
+		Version string     `json:"version"`
+		Creator harCreator `json:"creator"`
+		Entries []harEntry `json:"entries"`
+	} `json:"log"`
+}
+
+type harCreator struct {
+	Name    string `json:"name"`
+	Version string `json:"version"`
+}
+
+type harEntry struct {
+	StartedDateTime string      `json:"startedDateTime"`
+	Time            float64     `json:"time"`
+	Request         harRequest  `json:"request"`
+	Response        harResponse `json:"response"`
+	Cache           struct{}    `json:"cache"`
+	Timings         harTimings  `json:"timings"`
+}
+
+type harRequest struct {
+	Method      string         `json:"method"`
+	URL         string         `json:"url"`
+	HTTPVersion string         `json:"httpVersion"`
+	Cookies     []harNameValue `json:"cookies"`
+	Headers     []harNameValue `json:"headers"`
+	QueryString []harNameValue `json:"queryString"`
+	PostData    *harPostData   `json:"postData,omitempty"`
+	HeadersSize int            `json:"headersSize"`
+	BodySize    int            `json:"bodySize"`
+}
+
+type harResponse struct {
+	Status      int            `json:"status"`
+	StatusText  string         `json:"statusText"`
+	HTTPVersion string         `json:"httpVersion"`
+	Cookies     []harNameValue `json:"cookies"`
+	Headers     []harNameValue `json:"headers"`
+	Content     harContent     `json:"content"`
+	RedirectURL string         `json:"redirectURL"`
+	HeadersSize int            `json:"headersSize"`
+	BodySize    int            `json:"bodySize"`
+}
+
+type harNameValue struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
+
+type harPostData struct {
+	MimeType string `json:"mimeType"`
+	Text     string `json:"text"`
+}
+
+type harContent struct {
+	Size     int    `json:"size"`
+	MimeType string `json:"mimeType"`
+	Text     string `json:"text"`
+}
+
+type harTimings struct {
+	Send    float64 `json:"send"`
+	Wait    float64 `json:"wait"`
+	Receive float64 `json:"receive"`
+}
+
+func NewHARRecorder() *HARRecorder {
+	return &HARRecorder{enabled: true}
+}
+
+func (r *HARRecorder) Start() {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	r.enabled = true
+}
+
+func (r *HARRecorder) Stop() {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	r.enabled = false
+}
+
+func (r *HARRecorder) Reset() {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	r.entries = nil
+}
+
+func (r *HARRecorder) Enabled() bool {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	return r.enabled
+}
+
+func (r *HARRecorder) WriteTo(w io.Writer) (int64, error) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	var l harLog
+
+	l.Log.Version = "1.2"
+	l.Log.Creator = harCreator{Name: "stdsdk", Version: "1"}
+	l.Log.Entries = r.entries
+
+	if l.Log.Entries == nil {
+		l.Log.Entries = []harEntry{}
+	}
+
+	data, err := json.MarshalIndent(l, "", "  ")
+	if err != nil {
+		return 0, err
+	}
+
+	n, err := w.Write(data)
+
+	return int64(n), err
+}
+
+func (r *HARRecorder) Save(path string) error {
+	fd, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+
+	defer fd.Close()
+
+	if _, err := r.WriteTo(fd); err != nil {
+		return err
+	}
+
+	return fd.Close()
+}
+
+// record tees the response body and adds the entry once it has been read
+// or closed, so streaming responses are not held up by the recorder
+func (r *HARRecorder) record(req *http.Request, reqBody []byte, res *http.Response, started time.Time, elapsed time.Duration) {
+	e := harEntry{
+		StartedDateTime: started.Format(time.RFC3339Nano),
+		Time:            float64(elapsed) / float64(time.Millisecond),
+		Request: harRequest{
+			Method:      req.Method,
+			URL:         redactRequestURL(req),
+			HTTPVersion: req.Proto,
+			Cookies:     []harNameValue{},
+			Headers:     harHeaders(req.Header, sensitiveFor(req)),
+			QueryString: []harNameValue{},
+			HeadersSize: -1,
+			BodySize:    len(reqBody),
+		},
+		Response: harResponse{
+			Status:      res.StatusCode,
+			StatusText:  http.StatusText(res.StatusCode),
+			HTTPVersion: res.Proto,
+			Cookies:     []harNameValue{},
+			Headers:     harHeaders(res.Header, sensitiveFor(req)),
+			Content: harContent{
+				MimeType: res.Header.Get("Content-Type"),
+			},
+			RedirectURL: res.Header.Get("Location"),
+			HeadersSize: -1,
+		},
+	}
+
+	e.Timings.Wait = e.Time
+
+	for k, vs := range req.URL.Query() {
+		for _, v := range vs {
+			e.Request.QueryString = append(e.Request.QueryString, harNameValue{Name: k, Value: redactParam(req, k, v)})
+		}
+	}
+
+	if len(reqBody) > 0 {
+		e.Request.PostData = &harPostData{MimeType: req.Header.Get("Content-Type"), Text: string(reqBody)}
+	}
+
+	add := func(body []byte, size int) {
+		e.Response.Content.Size = size
+		e.Response.Content.Text = string(body)
+		e.Response.BodySize = size
+
+		r.lock.Lock()
+		defer r.lock.Unlock()
+
+		r.entries = append(r.entries, e)
+	}
+
+	if res.Body == nil {
+		add(nil, 0)
+		return
+	}
+
+	res.Body = &harBody{ReadCloser: res.Body, add: add}
+}
+
+// only the start of large bodies is kept, Size still reports the full length
+const harBodyLimit = 1024 * 1024
+
+type harBody struct {
+	io.ReadCloser
+	add  func([]byte, int)
+	buf  bytes.Buffer
+	once sync.Once
+	size int
+}
+
+func (b *harBody) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+
+	b.size += n
+
+	if room := harBodyLimit - b.buf.Len(); room > 0 {
+		if n < room {
+			room = n
+		}
+		b.buf.Write(p[:room])
+	}
+
+	if err == io.EOF {
+		b.finish()
+	}
+
+	return n, err
+}
+
+func (b *harBody) Close() error {
+	b.finish()
+	return b.ReadCloser.Close()
+}
+
+func (b *harBody) finish() {
+	b.once.Do(func() {
+		b.add(b.buf.Bytes(), b.size)
+	})
+}
+
+func (r *HARRecorder) bufferRequest(req *http.Request) ([]byte, error) {
+	if req.Body == nil {
+		return nil, nil
+	}
+
+	data, err := ioutil.ReadAll(req.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	req.Body.Close()
+	req.Body = ioutil.NopCloser(bytes.NewReader(data))
+
+	return data, nil
+}
+
+func harHeaders(h http.Header, sensitive func(string) bool) []harNameValue {
+	nvs := []harNameValue{}
+
+	for k, vs := range h {
+		for _, v := range vs {
+			if sensitive(k) {
+				v = redacted
+			}
+			nvs = append(nvs, harNameValue{Name: k, Value: v})
+		}
+	}
+
+	return nvs
+}
@@ -0,0 +1,35 @@
+package stdsdk
+
+import (
+	"bytes"
+	"encoding/json"
+)
+
+// JSONCodec replaces encoding/json, Unmarshal may keep references into data
+type JSONCodec interface {
+	Marshal(v interface{}) ([]byte, error)
+	Unmarshal(data []byte, v interface{}) error
+}
+
+// NumberDecoder is implemented by codecs able to decode numbers as json.Number
+type NumberDecoder interface {
+	UnmarshalUseNumber(data []byte, v interface{}) error
+}
+
+var JSON JSONCodec = StandardJSON{}
+
+type StandardJSON struct{}
+
+func (StandardJSON) Marshal(v interface{}) ([]byte, error) {
+	return json.Marshal(v)
+}
+
+func (StandardJSON) Unmarshal(data []byte, v interface{}) error {
+	return json.Unmarshal(data, v)
+}
+
+func (StandardJSON) UnmarshalUseNumber(data []byte, v interface{}) error {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	return dec.Decode(v)
+}
@@ -0,0 +1,55 @@
+package stdsdk_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/liamdawson/stdsdk"
+)
+
+// retainingJSON keeps data without copying, as zero-copy decoders do
+type retainingJSON struct {
+	stdsdk.StandardJSON
+}
+
+func (retainingJSON) Unmarshal(data []byte, v interface{}) error {
+	if r, ok := v.(*retained); ok {
+		*r = data
+		return nil
+	}
+
+	return stdsdk.StandardJSON{}.Unmarshal(data, v)
+}
+
+type retained []byte
+
+func TestCustomCodecKeepsInput(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`"` + r.URL.Path + `"`))
+	}))
+	defer ts.Close()
+
+	c, err := stdsdk.New(ts.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer func(j stdsdk.JSONCodec) { stdsdk.JSON = j }(stdsdk.JSON)
+	stdsdk.JSON = retainingJSON{}
+
+	var first, second retained
+
+	if err := c.Get("/first", stdsdk.RequestOptions{}, &first); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := c.Get("/other", stdsdk.RequestOptions{}, &second); err != nil {
+		t.Fatal(err)
+	}
+
+	if string(first) != `"/first"` {
+		t.Errorf("first = %s, overwritten by a later response", first)
+	}
+}
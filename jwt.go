@@ -0,0 +1,251 @@
+package stdsdk
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/asn1"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"net/http"
+	"sync"
+	"time"
+)
+
+type JWTAuth struct {
+	Algorithm  string
+	Audience   string
+	Claims     map[string]interface{}
+	Clock      Clock
+	Issuer     string
+	Key        crypto.Signer
+	KeyID      string
+	Lifetime   time.Duration
+	PerRequest bool
+	Subject    string
+
+	lock  sync.Mutex
+	cache *CachedTokenSource
+}
+
+func (j *JWTAuth) Apply(ctx context.Context, req *http.Request) error {
+	ctx = withClock(ctx, contextClock(ctx, j.Clock))
+
+	var t *Token
+	var err error
+
+	if j.PerRequest {
+		t, err = j.mint(ctx)
+	} else {
+		t, err = j.source().Token(ctx)
+	}
+	if err != nil {
+		return err
+	}
+
+	req.Header.Set("Authorization", "Bearer "+t.Value)
+
+	return nil
+}
+
+// Token signs a new token issued at now
+func (j *JWTAuth) Token(now time.Time) (string, error) {
+	return j.sign(now, now.Add(j.lifetime()))
+}
+
+// source caches tokens and renews them in the background in the last tenth
+// of their lifetime
+func (j *JWTAuth) source() *CachedTokenSource {
+	j.lock.Lock()
+	defer j.lock.Unlock()
+
+	if j.cache == nil {
+		j.cache = NewCachedTokenSource(TokenSourceFunc(j.mint), j.lifetime()/10)
+	}
+
+	return j.cache
+}
+
+// mint signs with the server clock, the expiry is local time for the cache
+func (j *JWTAuth) mint(ctx context.Context) (*Token, error) {
+	token, err := j.Token(contextClock(ctx, j.Clock).Now())
+	if err != nil {
+		return nil, err
+	}
+
+	return &Token{Value: token, Expiry: localClock(ctx, j.Clock).Now().Add(j.lifetime())}, nil
+}
+
+func (j *JWTAuth) lifetime() time.Duration {
+	if j.Lifetime > 0 {
+		return j.Lifetime
+	}
+
+	return 5 * time.Minute
+}
+
+// algorithm picks the default for the key, an explicit Algorithm must suit it
+func (j *JWTAuth) algorithm() (string, error) {
+	var alg string
+
+	switch pub := j.Key.Public().(type) {
+	case *rsa.PublicKey:
+		alg = "RS256"
+		if j.Algorithm == "RS512" || j.Algorithm == "PS256" {
+			alg = j.Algorithm
+		}
+	case *ecdsa.PublicKey:
+		switch pub.Curve {
+		case elliptic.P256():
+			alg = "ES256"
+		case elliptic.P384():
+			alg = "ES384"
+		case elliptic.P521():
+			alg = "ES512"
+		default:
+			return "", fmt.Errorf("unsupported jwt ecdsa curve: %s", pub.Curve.Params().Name)
+		}
+	case ed25519.PublicKey:
+		alg = "EdDSA"
+	default:
+		return "", fmt.Errorf("unknown jwt key type: %T", j.Key)
+	}
+
+	if j.Algorithm != "" && j.Algorithm != alg {
+		return "", fmt.Errorf("jwt algorithm %s does not match %T key", j.Algorithm, j.Key)
+	}
+
+	return alg, nil
+}
+
+func (j *JWTAuth) sign(iat, exp time.Time) (string, error) {
+	if j.Key == nil {
+		return "", fmt.Errorf("jwt key required")
+	}
+
+	alg, err := j.algorithm()
+	if err != nil {
+		return "", err
+	}
+
+	header := map[string]string{"alg": alg, "typ": "JWT"}
+
+	if j.KeyID != "" {
+		header["kid"] = j.KeyID
+	}
+
+	jti, err := randomHex(16)
+	if err != nil {
+		return "", err
+	}
+
+	claims := map[string]interface{}{}
+
+	for k, v := range j.Claims {
+		claims[k] = v
+	}
+
+	claims["iat"] = iat.Unix()
+	claims["exp"] = exp.Unix()
+	claims["jti"] = jti
+
+	if j.Issuer != "" {
+		claims["iss"] = j.Issuer
+	}
+
+	if j.Subject != "" {
+		claims["sub"] = j.Subject
+	}
+
+	if j.Audience != "" {
+		claims["aud"] = j.Audience
+	}
+
+	hd, err := json.Marshal(header)
+	if err != nil {
+		return "", err
+	}
+
+	cd, err := json.Marshal(claims)
+	if err != nil {
+		return "", err
+	}
+
+	input := b64url(hd) + "." + b64url(cd)
+
+	sig, err := signJWT(alg, j.Key, []byte(input))
+	if err != nil {
+		return "", err
+	}
+
+	return input + "." + b64url(sig), nil
+}
+
+func signJWT(alg string, key crypto.Signer, input []byte) ([]byte, error) {
+	switch alg {
+	case "RS256", "PS256":
+		sum := sha256.Sum256(input)
+		var opts crypto.SignerOpts = crypto.SHA256
+		if alg == "PS256" {
+			opts = &rsa.PSSOptions{SaltLength: rsa.PSSSaltLengthEqualsHash, Hash: crypto.SHA256}
+		}
+		return key.Sign(rand.Reader, sum[:], opts)
+	case "RS512":
+		sum := sha512.Sum512(input)
+		return key.Sign(rand.Reader, sum[:], crypto.SHA512)
+	case "ES256":
+		sum := sha256.Sum256(input)
+		return signECDSA(key, sum[:], crypto.SHA256, 32)
+	case "ES384":
+		sum := sha512.Sum384(input)
+		return signECDSA(key, sum[:], crypto.SHA384, 48)
+	case "ES512":
+		sum := sha512.Sum512(input)
+		return signECDSA(key, sum[:], crypto.SHA512, 66)
+	case "EdDSA":
+		return key.Sign(rand.Reader, input, crypto.Hash(0))
+	default:
+		return nil, fmt.Errorf("unsupported jwt algorithm: %s", alg)
+	}
+}
+
+// signECDSA converts the asn.1 signature of key into the fixed size r || s
+// form jws requires, size is the byte length of the curve order
+func signECDSA(key crypto.Signer, digest []byte, hash crypto.Hash, size int) ([]byte, error) {
+	der, err := key.Sign(rand.Reader, digest, hash)
+	if err != nil {
+		return nil, err
+	}
+
+	var sig struct {
+		R, S *big.Int
+	}
+
+	if _, err := asn1.Unmarshal(der, &sig); err != nil {
+		return nil, fmt.Errorf("invalid ecdsa signature: %w", err)
+	}
+
+	return append(padInt(sig.R, size), padInt(sig.S, size)...), nil
+}
+
+func padInt(i *big.Int, n int) []byte {
+	b := i.Bytes()
+
+	if len(b) >= n {
+		return b
+	}
+
+	return append(make([]byte, n-len(b)), b...)
+}
+
+func b64url(data []byte) string {
+	return base64.RawURLEncoding.EncodeToString(data)
+}
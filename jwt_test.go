@@ -0,0 +1,93 @@
+package stdsdk_test
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/liamdawson/stdsdk"
+)
+
+func TestJWTECDSACurves(t *testing.T) {
+	tests := []struct {
+		curve elliptic.Curve
+		alg   string
+		size  int
+		hash  func([]byte) []byte
+	}{
+		{elliptic.P256(), "ES256", 32, func(b []byte) []byte { s := sha256.Sum256(b); return s[:] }},
+		{elliptic.P384(), "ES384", 48, func(b []byte) []byte { s := sha512.Sum384(b); return s[:] }},
+		{elliptic.P521(), "ES512", 66, func(b []byte) []byte { s := sha512.Sum512(b); return s[:] }},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.alg, func(t *testing.T) {
+			key, err := ecdsa.GenerateKey(tt.curve, rand.Reader)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			token, err := (&stdsdk.JWTAuth{Key: key}).Token(time.Now())
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			parts := strings.Split(token, ".")
+
+			hd, _ := base64.RawURLEncoding.DecodeString(parts[0])
+
+			var header map[string]string
+
+			if err := json.Unmarshal(hd, &header); err != nil {
+				t.Fatal(err)
+			}
+
+			if header["alg"] != tt.alg {
+				t.Errorf("alg = %s, want %s", header["alg"], tt.alg)
+			}
+
+			sig, _ := base64.RawURLEncoding.DecodeString(parts[2])
+
+			if len(sig) != 2*tt.size {
+				t.Fatalf("signature length = %d, want %d", len(sig), 2*tt.size)
+			}
+
+			r := new(big.Int).SetBytes(sig[:tt.size])
+			s := new(big.Int).SetBytes(sig[tt.size:])
+
+			if !ecdsa.Verify(&key.PublicKey, tt.hash([]byte(parts[0]+"."+parts[1])), r, s) {
+				t.Error("signature does not verify")
+			}
+		})
+	}
+}
+
+func TestJWTAlgorithmMismatch(t *testing.T) {
+	ec, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	_, ed, _ := ed25519.GenerateKey(rand.Reader)
+
+	tests := []struct {
+		alg string
+		key crypto.Signer
+	}{
+		{"RS256", ec},
+		{"ES384", ec},
+		{"ES256", ed},
+	}
+
+	for _, tt := range tests {
+		if _, err := (&stdsdk.JWTAuth{Algorithm: tt.alg, Key: tt.key}).Token(time.Now()); err == nil {
+			t.Errorf("%s with %T: expected error", tt.alg, tt.key)
+		}
+	}
+}
@@ -0,0 +1,145 @@
+package stdsdk
+
+import (
+	"context"
+	"errors"
+	"math"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// Limiter bounds in-flight requests, done reports how the request went so
+// adaptive limiters can adjust
+type Limiter interface {
+	Acquire(ctx context.Context) (done func(latency time.Duration, dropped bool), err error)
+}
+
+// AIMDLimiter grows the limit by one per window of successful requests and
+// cuts it by Backoff when a request fails, is throttled or exceeds
+// LatencyThreshold
+type AIMDLimiter struct {
+	Backoff          float64
+	Initial          int
+	LatencyThreshold time.Duration
+	Max              int
+	Min              int
+
+	lock     sync.Mutex
+	inflight int
+	limit    float64
+	waiters  []chan struct{}
+}
+
+func NewAIMDLimiter(initial, min, max int) *AIMDLimiter {
+	return &AIMDLimiter{Initial: initial, Max: max, Min: min}
+}
+
+func (l *AIMDLimiter) Limit() int {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+
+	l.init()
+
+	return int(l.limit)
+}
+
+func (l *AIMDLimiter) Acquire(ctx context.Context) (func(time.Duration, bool), error) {
+	l.lock.Lock()
+
+	l.init()
+
+	for l.inflight >= int(l.limit) {
+		ch := make(chan struct{})
+		l.waiters = append(l.waiters, ch)
+		l.lock.Unlock()
+
+		select {
+		case <-ctx.Done():
+			l.lock.Lock()
+			l.remove(ch)
+			l.lock.Unlock()
+			return nil, ctx.Err()
+		case <-ch:
+		}
+
+		l.lock.Lock()
+	}
+
+	l.inflight++
+	l.lock.Unlock()
+
+	var once sync.Once
+
+	return func(latency time.Duration, dropped bool) {
+		once.Do(func() { l.release(latency, dropped) })
+	}, nil
+}
+
+func (l *AIMDLimiter) init() {
+	if l.limit > 0 {
+		return
+	}
+
+	if l.Min <= 0 {
+		l.Min = 1
+	}
+
+	if l.Max <= 0 {
+		l.Max = 1000
+	}
+
+	if l.Backoff <= 0 || l.Backoff >= 1 {
+		l.Backoff = 0.9
+	}
+
+	l.limit = float64(l.Initial)
+
+	if l.limit <= 0 {
+		l.limit = 10
+	}
+
+	l.limit = math.Min(math.Max(l.limit, float64(l.Min)), float64(l.Max))
+}
+
+func (l *AIMDLimiter) release(latency time.Duration, dropped bool) {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+
+	l.inflight--
+
+	if l.LatencyThreshold > 0 && latency > l.LatencyThreshold {
+		dropped = true
+	}
+
+	if dropped {
+		l.limit = math.Max(float64(l.Min), l.limit*l.Backoff)
+	} else {
+		l.limit = math.Min(float64(l.Max), l.limit+1/l.limit)
+	}
+
+	// waiters re-check the limit once woken
+	for _, w := range l.waiters {
+		close(w)
+	}
+
+	l.waiters = nil
+}
+
+func (l *AIMDLimiter) remove(ch chan struct{}) {
+	for i, w := range l.waiters {
+		if w == ch {
+			l.waiters = append(l.waiters[:i], l.waiters[i+1:]...)
+			return
+		}
+	}
+}
+
+// callers giving up are not a signal of server overload
+func limitDropped(res *http.Response, err error) bool {
+	if err != nil {
+		return !errors.Is(err, context.Canceled)
+	}
+
+	return res.StatusCode == http.StatusTooManyRequests || res.StatusCode >= 500
+}
@@ -0,0 +1,113 @@
+package stdsdk
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Metrics receives client measurements, implementations must be safe for
+// concurrent use and should not block
+type Metrics interface {
+	Counter(name string, value int64, tags map[string]string)
+	Gauge(name string, value float64, tags map[string]string)
+	Timer(name string, d time.Duration, tags map[string]string)
+}
+
+func (c *Client) observeMetrics(req *http.Request, res *http.Response, err error, d time.Duration) {
+	if c.Metrics == nil {
+		return
+	}
+
+	tags := map[string]string{"host": req.URL.Host, "method": req.Method}
+
+	if t, ok := req.Context().Value(templateKey{}).(string); ok {
+		tags["path"] = t
+	}
+
+	if err != nil {
+		tags["status"] = "error"
+	} else {
+		tags["status"] = strconv.Itoa(res.StatusCode)
+	}
+
+	c.Metrics.Counter("stdsdk.requests", 1, tags)
+	c.Metrics.Timer("stdsdk.request.duration", d, tags)
+	c.Metrics.Gauge("stdsdk.requests.in_flight", float64(c.Stats().InFlight), map[string]string{"host": req.URL.Host})
+}
+
+func (c *Client) retry(req *http.Request) {
+	c.stats.retry()
+
+	if c.Metrics != nil {
+		c.Metrics.Counter("stdsdk.retries", 1, map[string]string{"host": req.URL.Host, "method": req.Method})
+	}
+}
+
+// StatsD sends metrics over udp, Datadog adds tags in the dogstatsd format
+// which plain statsd servers do not understand
+type StatsD struct {
+	Datadog bool
+	Prefix  string
+
+	conn net.Conn
+	lock sync.Mutex
+}
+
+func NewStatsD(addr string) (*StatsD, error) {
+	conn, err := net.Dial("udp", addr)
+	if err != nil {
+		return nil, fmt.Errorf("could not connect to statsd: %w", err)
+	}
+
+	return &StatsD{conn: conn}, nil
+}
+
+func (s *StatsD) Counter(name string, value int64, tags map[string]string) {
+	s.send(name, strconv.FormatInt(value, 10), "c", tags)
+}
+
+func (s *StatsD) Gauge(name string, value float64, tags map[string]string) {
+	s.send(name, strconv.FormatFloat(value, 'f', -1, 64), "g", tags)
+}
+
+func (s *StatsD) Timer(name string, d time.Duration, tags map[string]string) {
+	s.send(name, strconv.FormatFloat(float64(d)/float64(time.Millisecond), 'f', -1, 64), "ms", tags)
+}
+
+func (s *StatsD) Close() error {
+	return s.conn.Close()
+}
+
+// send drops the metric on error, losing a sample is preferable to failing a request
+func (s *StatsD) send(name, value, typ string, tags map[string]string) {
+	line := fmt.Sprintf("%s%s:%s|%s", s.Prefix, name, value, typ)
+
+	if s.Datadog && len(tags) > 0 {
+		keys := make([]string, 0, len(tags))
+
+		for k := range tags {
+			keys = append(keys, k)
+		}
+
+		sort.Strings(keys)
+
+		parts := make([]string, len(keys))
+
+		for i, k := range keys {
+			parts[i] = fmt.Sprintf("%s:%s", k, tags[k])
+		}
+
+		line += "|#" + strings.Join(parts, ",")
+	}
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	s.conn.Write([]byte(line))
+}
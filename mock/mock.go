@@ -0,0 +1,306 @@
+package mock
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"reflect"
+	"strings"
+	"sync"
+
+	"github.com/liamdawson/stdsdk"
+)
+
+type Client struct {
+	// Decoder supplies decoding settings such as Envelope and UseNumber,
+	// nil decodes like a client with none set
+	Decoder *stdsdk.Client
+	Strict  bool
+
+	expectations []*Expectation
+	lock         sync.Mutex
+	unexpected   []string
+}
+
+type Expectation struct {
+	body     []byte
+	calls    int
+	err      error
+	matchers []Matcher
+	method   string
+	path     string
+	status   int
+	times    int
+}
+
+type Matcher func(opts stdsdk.RequestOptions) bool
+
+var _ stdsdk.API = &Client{}
+
+func New() *Client {
+	return &Client{Strict: true}
+}
+
+func (c *Client) Expect(method, path string) *Expectation {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	e := &Expectation{method: method, path: path, status: 200, times: 1}
+
+	c.expectations = append(c.expectations, e)
+
+	return e
+}
+
+func (e *Expectation) With(m ...Matcher) *Expectation {
+	e.matchers = append(e.matchers, m...)
+	return e
+}
+
+func (e *Expectation) Return(v interface{}) *Expectation {
+	data, err := json.Marshal(v)
+	if err != nil {
+		panic(err)
+	}
+	e.body = data
+	return e
+}
+
+func (e *Expectation) ReturnBody(data []byte) *Expectation {
+	e.body = data
+	return e
+}
+
+func (e *Expectation) ReturnError(err error) *Expectation {
+	e.err = err
+	return e
+}
+
+func (e *Expectation) Status(code int) *Expectation {
+	e.status = code
+	return e
+}
+
+func (e *Expectation) Times(n int) *Expectation {
+	e.times = n
+	return e
+}
+
+func (e *Expectation) AnyTimes() *Expectation {
+	e.times = -1
+	return e
+}
+
+func Header(k, v string) Matcher {
+	return func(opts stdsdk.RequestOptions) bool {
+		return opts.Headers[k] == v
+	}
+}
+
+func Param(k string, v interface{}) Matcher {
+	return func(opts stdsdk.RequestOptions) bool {
+		return reflect.DeepEqual(opts.Params[k], v)
+	}
+}
+
+func Query(k string, v interface{}) Matcher {
+	return func(opts stdsdk.RequestOptions) bool {
+		return reflect.DeepEqual(opts.Query[k], v)
+	}
+}
+
+func (c *Client) Close() error {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	errs := append([]string{}, c.unexpected...)
+
+	for _, e := range c.expectations {
+		if e.times > 0 && e.calls < e.times {
+			errs = append(errs, fmt.Sprintf("expected %s %s %d times, got %d", e.method, e.path, e.times, e.calls))
+		}
+	}
+
+	if len(errs) > 0 {
+		return fmt.Errorf("unmet expectations:\n  %s", strings.Join(errs, "\n  "))
+	}
+
+	return nil
+}
+
+func (c *Client) Delete(path string, opts stdsdk.RequestOptions, out interface{}) error {
+	return c.decode("DELETE", path, opts, out)
+}
+
+func (c *Client) DeleteStream(path string, opts stdsdk.RequestOptions) (*stdsdk.Response, error) {
+	return c.stream("DELETE", path, opts)
+}
+
+func (c *Client) Get(path string, opts stdsdk.RequestOptions, out interface{}) error {
+	return c.decode("GET", path, opts, out)
+}
+
+func (c *Client) GetStream(path string, opts stdsdk.RequestOptions) (*stdsdk.Response, error) {
+	return c.stream("GET", path, opts)
+}
+
+func (c *Client) Head(path string, opts stdsdk.RequestOptions, out *bool) error {
+	e, err := c.call("HEAD", path, opts)
+	if err != nil {
+		return err
+	}
+
+	// like the real client a missing resource is false, other errors fail
+	if e != nil && e.status >= 400 && e.status != 404 {
+		return c.decoder().Decode(e.response(), nil, opts)
+	}
+
+	*out = e != nil && e.status/100 == 2
+
+	return nil
+}
+
+func (c *Client) HeadMetadata(path string, opts stdsdk.RequestOptions) (*stdsdk.Metadata, error) {
+	e, err := c.call("HEAD", path, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	m := &stdsdk.Metadata{Header: http.Header{}, StatusCode: 200}
+
+	if e != nil {
+		m.StatusCode = e.status
+		m.ContentLength = int64(len(e.body))
+	}
+
+	return m, nil
+}
+
+func (c *Client) Options(path string, opts stdsdk.RequestOptions, out interface{}) error {
+	return c.decode("OPTIONS", path, opts, out)
+}
+
+func (c *Client) Post(path string, opts stdsdk.RequestOptions, out interface{}) error {
+	return c.decode("POST", path, opts, out)
+}
+
+func (c *Client) PostStream(path string, opts stdsdk.RequestOptions) (*stdsdk.Response, error) {
+	return c.stream("POST", path, opts)
+}
+
+func (c *Client) Put(path string, opts stdsdk.RequestOptions, out interface{}) error {
+	return c.decode("PUT", path, opts, out)
+}
+
+func (c *Client) PutStream(path string, opts stdsdk.RequestOptions) (*stdsdk.Response, error) {
+	return c.stream("PUT", path, opts)
+}
+
+func (c *Client) Websocket(path string, opts stdsdk.RequestOptions) (io.ReadCloser, error) {
+	e, err := c.call("WEBSOCKET", path, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	if e == nil {
+		return ioutil.NopCloser(bytes.NewReader(nil)), nil
+	}
+
+	return ioutil.NopCloser(bytes.NewReader(e.body)), nil
+}
+
+func (c *Client) call(method, path string, opts stdsdk.RequestOptions) (*Expectation, error) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	for _, e := range c.expectations {
+		if e.method != method || e.path != path {
+			continue
+		}
+
+		if e.times >= 0 && e.calls >= e.times {
+			continue
+		}
+
+		if !e.matches(opts) {
+			continue
+		}
+
+		e.calls++
+
+		if e.err != nil {
+			return nil, e.err
+		}
+
+		return e, nil
+	}
+
+	if c.Strict {
+		msg := fmt.Sprintf("unexpected call: %s %s", method, path)
+		c.unexpected = append(c.unexpected, msg)
+		return nil, fmt.Errorf("%s", msg)
+	}
+
+	return nil, nil
+}
+
+func (c *Client) decode(method, path string, opts stdsdk.RequestOptions, out interface{}) error {
+	e, err := c.call(method, path, opts)
+	if err != nil {
+		return err
+	}
+
+	if e == nil || (len(e.body) == 0 && e.status < 400) {
+		return nil
+	}
+
+	return c.decoder().Decode(e.response(), out, opts)
+}
+
+func (c *Client) decoder() *stdsdk.Client {
+	if c.Decoder != nil {
+		return c.Decoder
+	}
+
+	return &stdsdk.Client{}
+}
+
+func (c *Client) stream(method, path string, opts stdsdk.RequestOptions) (*stdsdk.Response, error) {
+	e, err := c.call(method, path, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	if e == nil {
+		e = &Expectation{status: 200}
+	}
+
+	if e.status >= 400 {
+		return nil, c.decoder().Decode(e.response(), nil, opts)
+	}
+
+	return &stdsdk.Response{Response: e.response()}, nil
+}
+
+func (e *Expectation) response() *http.Response {
+	return &http.Response{
+		Body:          ioutil.NopCloser(bytes.NewReader(e.body)),
+		ContentLength: int64(len(e.body)),
+		Header:        http.Header{},
+		Status:        fmt.Sprintf("%d %s", e.status, http.StatusText(e.status)),
+		StatusCode:    e.status,
+	}
+}
+
+func (e *Expectation) matches(opts stdsdk.RequestOptions) bool {
+	for _, m := range e.matchers {
+		if !m(opts) {
+			return false
+		}
+	}
+
+	return true
+}
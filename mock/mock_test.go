@@ -0,0 +1,62 @@
+package mock_test
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+
+	"github.com/liamdawson/stdsdk"
+	"github.com/liamdawson/stdsdk/mock"
+)
+
+// Head behaves the same against the mock and a real server
+func TestHeadMatchesClient(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n, _ := strconv.Atoi(r.URL.Path[1:])
+		w.WriteHeader(n)
+	}))
+	defer ts.Close()
+
+	client, err := stdsdk.New(ts.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		status int
+		exists bool
+		err    bool
+	}{
+		{200, true, false},
+		{404, false, false},
+		{403, false, true},
+		{500, false, true},
+	}
+
+	for _, tt := range tests {
+		path := "/" + strconv.Itoa(tt.status)
+
+		m := mock.New()
+		m.Expect("HEAD", path).Status(tt.status)
+
+		for name, c := range map[string]stdsdk.API{"client": client, "mock": m} {
+			exists := !tt.exists
+			err := c.Head(path, stdsdk.RequestOptions{}, &exists)
+
+			if (err != nil) != tt.err {
+				t.Errorf("%s %d: err = %v, want error %t", name, tt.status, err, tt.err)
+			}
+
+			var re *stdsdk.ResponseError
+			if err != nil && (!errors.As(err, &re) || re.Status != tt.status) {
+				t.Errorf("%s %d: err = %#v, want *ResponseError", name, tt.status, err)
+			}
+
+			if err == nil && exists != tt.exists {
+				t.Errorf("%s %d: exists = %t, want %t", name, tt.status, exists, tt.exists)
+			}
+		}
+	}
+}
@@ -0,0 +1,89 @@
+package stdsdk
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+)
+
+// NegotiateProvider produces SPNEGO tokens, typically backed by a kerberos
+// library, for the given service principal and server challenge
+type NegotiateProvider interface {
+	Token(spn string, challenge []byte) ([]byte, error)
+}
+
+type NegotiateAuth struct {
+	Provider NegotiateProvider
+
+	lock      sync.Mutex
+	active    bool
+	challenge []byte
+}
+
+var _ UnauthorizedHandler = &NegotiateAuth{}
+
+func (n *NegotiateAuth) OnUnauthorized(ctx context.Context, res *http.Response) (bool, error) {
+	var challenge []byte
+
+	found := false
+
+	for _, v := range res.Header.Values("WWW-Authenticate") {
+		fields := strings.Fields(v)
+
+		if len(fields) == 0 || !strings.EqualFold(fields[0], "Negotiate") {
+			continue
+		}
+
+		found = true
+
+		if len(fields) > 1 {
+			data, err := base64.StdEncoding.DecodeString(fields[1])
+			if err != nil {
+				return false, fmt.Errorf("invalid negotiate challenge: %w", err)
+			}
+			challenge = data
+		}
+
+		break
+	}
+
+	if !found {
+		return false, nil
+	}
+
+	// already sent a token and was still rejected
+	if res.Request != nil && strings.HasPrefix(res.Request.Header.Get("Authorization"), "Negotiate ") && challenge == nil {
+		return false, nil
+	}
+
+	n.lock.Lock()
+	defer n.lock.Unlock()
+
+	n.active = true
+	n.challenge = challenge
+
+	return true, nil
+}
+
+func (n *NegotiateAuth) Apply(ctx context.Context, req *http.Request) error {
+	n.lock.Lock()
+	active, challenge := n.active, n.challenge
+	n.challenge = nil
+	n.lock.Unlock()
+
+	if !active {
+		return nil
+	}
+
+	token, err := n.Provider.Token(fmt.Sprintf("HTTP/%s", req.URL.Hostname()), challenge)
+	if err != nil {
+		return err
+	}
+
+	req.Header.Set("Authorization", "Negotiate "+base64.StdEncoding.EncodeToString(token))
+
+	return nil
+}
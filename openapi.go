@@ -0,0 +1,332 @@
+package stdsdk
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"mime"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+// OpenAPI validates outgoing requests against an OpenAPI 3 document, it is
+// meant for development since every request is checked before it is sent
+type OpenAPI struct {
+	paths  []openAPIPath
+	schema *Schema
+}
+
+type openAPIPath struct {
+	operations map[string]map[string]interface{}
+	parameters []interface{}
+	segments   []string
+	template   string
+}
+
+type RequestValidationError struct {
+	Errors []string
+	Method string
+	Path   string
+}
+
+func (e *RequestValidationError) Error() string {
+	return fmt.Sprintf("invalid request %s %s: %s", e.Method, e.Path, strings.Join(e.Errors, "; "))
+}
+
+// LoadOpenAPI accepts the document as json or yaml
+func LoadOpenAPI(data []byte) (*OpenAPI, error) {
+	if t := bytes.TrimSpace(data); len(t) > 0 && t[0] != '{' {
+		var err error
+
+		if data, err = yamlToJSON(data); err != nil {
+			return nil, fmt.Errorf("invalid openapi document: %w", err)
+		}
+	}
+
+	s, err := ParseSchema(data)
+	if err != nil {
+		return nil, fmt.Errorf("invalid openapi document: %w", err)
+	}
+
+	doc, ok := s.root.(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("invalid openapi document")
+	}
+
+	paths, ok := doc["paths"].(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("invalid openapi document: missing paths")
+	}
+
+	o := &OpenAPI{schema: s}
+
+	for template, item := range paths {
+		pi, ok := item.(map[string]interface{})
+		if !ok {
+			continue
+		}
+
+		p := openAPIPath{
+			operations: map[string]map[string]interface{}{},
+			segments:   strings.Split(strings.Trim(template, "/"), "/"),
+			template:   template,
+		}
+
+		p.parameters, _ = pi["parameters"].([]interface{})
+
+		for method, op := range pi {
+			if m, ok := op.(map[string]interface{}); ok && method != "parameters" {
+				p.operations[strings.ToUpper(method)] = m
+			}
+		}
+
+		o.paths = append(o.paths, p)
+	}
+
+	return o, nil
+}
+
+// ValidateRequest checks req against the operation matching path, which is
+// relative to the servers base url of the document
+func (o *OpenAPI) ValidateRequest(req *http.Request, path string) error {
+	e := &RequestValidationError{Method: req.Method, Path: path}
+
+	p, vars := o.match(path)
+	if p == nil {
+		e.Errors = append(e.Errors, "no matching path in openapi document")
+		return e
+	}
+
+	op, ok := p.operations[req.Method]
+	if !ok {
+		e.Errors = append(e.Errors, fmt.Sprintf("method not allowed for %s", p.template))
+		return e
+	}
+
+	params, _ := op["parameters"].([]interface{})
+
+	for _, param := range append(append([]interface{}{}, p.parameters...), params...) {
+		o.checkParameter(e, param, req, vars)
+	}
+
+	if rb, ok := op["requestBody"]; ok {
+		o.checkBody(e, rb, req)
+	}
+
+	if len(e.Errors) > 0 {
+		return e
+	}
+
+	return nil
+}
+
+// match prefers the template with the most literal segments
+func (o *OpenAPI) match(path string) (*openAPIPath, map[string]string) {
+	segments := strings.Split(strings.Trim(path, "/"), "/")
+
+	var best *openAPIPath
+	var bestVars map[string]string
+	bestLiterals := -1
+
+	for i := range o.paths {
+		p := &o.paths[i]
+
+		if len(p.segments) != len(segments) {
+			continue
+		}
+
+		vars := map[string]string{}
+		literals := 0
+		matched := true
+
+		for j, s := range p.segments {
+			if strings.HasPrefix(s, "{") && strings.HasSuffix(s, "}") {
+				v, err := url.PathUnescape(segments[j])
+				if err != nil || v == "" {
+					matched = false
+					break
+				}
+				vars[s[1:len(s)-1]] = v
+				continue
+			}
+
+			if s != segments[j] {
+				matched = false
+				break
+			}
+
+			literals++
+		}
+
+		if matched && literals > bestLiterals {
+			best, bestVars, bestLiterals = p, vars, literals
+		}
+	}
+
+	return best, bestVars
+}
+
+func (o *OpenAPI) deref(node interface{}) map[string]interface{} {
+	m, _ := node.(map[string]interface{})
+
+	for i := 0; m != nil && i < 16; i++ {
+		ref, ok := m["$ref"].(string)
+		if !ok {
+			break
+		}
+
+		target, err := o.schema.resolve(ref)
+		if err != nil {
+			return nil
+		}
+
+		m, _ = target.(map[string]interface{})
+	}
+
+	return m
+}
+
+func (o *OpenAPI) checkParameter(e *RequestValidationError, node interface{}, req *http.Request, vars map[string]string) {
+	param := o.deref(node)
+	if param == nil {
+		return
+	}
+
+	name, _ := param["name"].(string)
+	in, _ := param["in"].(string)
+	required, _ := param["required"].(bool)
+
+	var values []string
+
+	switch in {
+	case "path":
+		if v, ok := vars[name]; ok {
+			values = []string{v}
+		}
+		required = true
+	case "query":
+		values = req.URL.Query()[name]
+	case "header":
+		values = req.Header.Values(name)
+	case "cookie":
+		if c, err := req.Cookie(name); err == nil {
+			values = []string{c.Value}
+		}
+	default:
+		return
+	}
+
+	if len(values) == 0 {
+		if required {
+			e.Errors = append(e.Errors, fmt.Sprintf("missing required %s parameter: %s", in, name))
+		}
+		return
+	}
+
+	schema, ok := param["schema"]
+	if !ok {
+		return
+	}
+
+	sch := o.deref(schema)
+
+	var doc interface{}
+
+	if typ, _ := sch["type"].(string); typ == "array" {
+		if len(values) == 1 {
+			values = strings.Split(values[0], ",")
+		}
+		items := []interface{}{}
+		for _, v := range values {
+			items = append(items, coerceParameter(o.deref(sch["items"]), v))
+		}
+		doc = items
+	} else {
+		doc = coerceParameter(sch, values[0])
+	}
+
+	for _, err := range o.schema.check(schema, doc, in+"."+name, false) {
+		e.Errors = append(e.Errors, err.Error())
+	}
+}
+
+// coerceParameter turns a raw parameter into the json value its schema
+// expects so it can be checked like a body
+func coerceParameter(sch map[string]interface{}, v string) interface{} {
+	typ, _ := sch["type"].(string)
+
+	switch typ {
+	case "integer", "number":
+		if _, err := strconv.ParseFloat(v, 64); err == nil {
+			return json.Number(v)
+		}
+	case "boolean":
+		if b, err := strconv.ParseBool(v); err == nil {
+			return b
+		}
+	}
+
+	return v
+}
+
+func (o *OpenAPI) checkBody(e *RequestValidationError, node interface{}, req *http.Request) {
+	rb := o.deref(node)
+	if rb == nil {
+		return
+	}
+
+	required, _ := rb["required"].(bool)
+
+	if req.Body == nil || req.Body == http.NoBody {
+		if required {
+			e.Errors = append(e.Errors, "missing required request body")
+		}
+		return
+	}
+
+	ct, _, _ := mime.ParseMediaType(req.Header.Get("Content-Type"))
+
+	content, _ := rb["content"].(map[string]interface{})
+
+	media, ok := content[ct].(map[string]interface{})
+	if !ok {
+		if len(content) > 0 {
+			e.Errors = append(e.Errors, fmt.Sprintf("unsupported content type: %s", ct))
+		}
+		return
+	}
+
+	schema, ok := media["schema"]
+	if !ok || !strings.HasSuffix(ct, "json") || req.GetBody == nil {
+		return
+	}
+
+	// read from a copy so the real body is left untouched
+	body, err := req.GetBody()
+	if err != nil {
+		return
+	}
+	defer body.Close()
+
+	data, err := ioutil.ReadAll(body)
+	if err != nil {
+		return
+	}
+
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+
+	var doc interface{}
+
+	if err := dec.Decode(&doc); err != nil {
+		e.Errors = append(e.Errors, fmt.Sprintf("invalid json body: %s", err))
+		return
+	}
+
+	for _, err := range o.schema.check(schema, doc, "body", false) {
+		e.Errors = append(e.Errors, err.Error())
+	}
+}
@@ -0,0 +1,74 @@
+package stdsdk_test
+
+import (
+	"bytes"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/liamdawson/stdsdk"
+)
+
+const openAPIYAML = `
+openapi: 3.0.0
+paths:
+  /apps/{app}:
+    parameters:
+      - name: app
+        in: path
+        required: true
+        schema:
+          type: string
+          minLength: 3
+    put:
+      requestBody:
+        required: true
+        content:
+          application/json:
+            schema:
+              $ref: '#/components/schemas/App'
+      responses:
+        200:
+          description: ok
+components:
+  schemas:
+    App:
+      type: object
+      required: [name]
+      properties:
+        name:
+          type: string
+`
+
+func TestLoadOpenAPIYAML(t *testing.T) {
+	o, err := stdsdk.LoadOpenAPI([]byte(openAPIYAML))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	put := func(path, body string) error {
+		req, err := http.NewRequest("PUT", "http://example.com"+path, bytes.NewReader([]byte(body)))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		req.Header.Set("Content-Type", "application/json")
+
+		return o.ValidateRequest(req, path)
+	}
+
+	if err := put("/apps/app1", `{"name":"a"}`); err != nil {
+		t.Errorf("valid request: %v", err)
+	}
+
+	err = put("/apps/a", `{}`)
+	if err == nil {
+		t.Fatal("expected validation error")
+	}
+
+	for _, want := range []string{"path.app: expected at least 3 characters", "body: missing required property: name"} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("error = %v, want %q", err, want)
+		}
+	}
+}
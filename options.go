@@ -5,27 +5,237 @@ import (
 	"fmt"
 	"io"
 	"mime/multipart"
+	"net/http"
 	"net/url"
 	"reflect"
+	"strconv"
 	"strings"
+	"sync"
 	"time"
 )
 
 type Files map[string][]byte
 type Headers map[string]string
 type Params map[string]interface{}
+type Path map[string]interface{}
 type Query map[string]interface{}
 
+type ArrayStyle int
+
+const (
+	ArrayDefault ArrayStyle = iota
+	ArrayRepeat
+	ArrayComma
+	ArrayBrackets
+)
+
+type OptionMarshaler interface {
+	MarshalOption() (string, error)
+}
+
 type RequestOptions struct {
-	Body    io.Reader
-	Files   Files
-	Headers Headers
-	Params  Params
-	Query   Query
+	ArrayStyle      ArrayStyle
+	Body            io.Reader
+	Buffer          *StreamBuffer
+	ContentLength   int64
+	Envelope        string
+	ExpectContinue  bool
+	Files           Files
+	Headers         Headers
+	Params          Params
+	Path            Path
+	Prepare         []PrepareFunc
+	Priority        Priority
+	Query           Query
+	ResponseHeaders *http.Header
+	Schema          *Schema
+	Timeout         time.Duration
+	TrailerError    string
+	UseNumber       bool
+}
+
+// Clone returns a copy with its own maps, Body is shared
+func (o *RequestOptions) Clone() RequestOptions {
+	c := *o
+
+	if o.Files != nil {
+		c.Files = Files{}
+		for k, v := range o.Files {
+			c.Files[k] = v
+		}
+	}
+
+	if o.Headers != nil {
+		c.Headers = Headers{}
+		for k, v := range o.Headers {
+			c.Headers[k] = v
+		}
+	}
+
+	if o.Prepare != nil {
+		c.Prepare = append([]PrepareFunc{}, o.Prepare...)
+	}
+
+	c.Params = Params(copyValues(o.Params))
+	c.Path = Path(copyValues(o.Path))
+	c.Query = Query(copyValues(o.Query))
+
+	return c
+}
+
+// Merge returns a new RequestOptions with values from other taking
+// precedence over o, neither input is modified
+func (o *RequestOptions) Merge(other RequestOptions) RequestOptions {
+	m := o.Clone()
+
+	if other.ArrayStyle != ArrayDefault {
+		m.ArrayStyle = other.ArrayStyle
+	}
+
+	if other.Body != nil {
+		m.Body = other.Body
+		m.ContentLength = other.ContentLength
+	}
+
+	if other.Buffer != nil {
+		m.Buffer = other.Buffer
+	}
+
+	if other.Envelope != "" {
+		m.Envelope = other.Envelope
+	}
+
+	if other.ExpectContinue {
+		m.ExpectContinue = true
+	}
+
+	m.Prepare = append(m.Prepare, other.Prepare...)
+
+	if other.Priority != PriorityNormal {
+		m.Priority = other.Priority
+	}
+
+	if other.ResponseHeaders != nil {
+		m.ResponseHeaders = other.ResponseHeaders
+	}
+
+	if other.Schema != nil {
+		m.Schema = other.Schema
+	}
+
+	if other.Timeout > 0 {
+		m.Timeout = other.Timeout
+	}
+
+	if other.TrailerError != "" {
+		m.TrailerError = other.TrailerError
+	}
+
+	if other.UseNumber {
+		m.UseNumber = true
+	}
+
+	for k, v := range other.Files {
+		if m.Files == nil {
+			m.Files = Files{}
+		}
+		m.Files[k] = v
+	}
+
+	for k, v := range other.Headers {
+		if m.Headers == nil {
+			m.Headers = Headers{}
+		}
+		m.Headers[k] = v
+	}
+
+	for k, v := range other.Params {
+		if m.Params == nil {
+			m.Params = Params{}
+		}
+		m.Params[k] = v
+	}
+
+	for k, v := range other.Path {
+		if m.Path == nil {
+			m.Path = Path{}
+		}
+		m.Path[k] = v
+	}
+
+	for k, v := range other.Query {
+		if m.Query == nil {
+			m.Query = Query{}
+		}
+		m.Query[k] = v
+	}
+
+	return m
+}
+
+func copyValues(vv map[string]interface{}) map[string]interface{} {
+	if vv == nil {
+		return nil
+	}
+
+	c := map[string]interface{}{}
+
+	for k, v := range vv {
+		c[k] = v
+	}
+
+	return c
+}
+
+func (o *RequestOptions) ExpandPath(path string) (string, error) {
+	if !strings.Contains(path, "{") {
+		return path, nil
+	}
+
+	uv, err := marshalValues(o.Path, ArrayDefault)
+	if err != nil {
+		return "", err
+	}
+
+	var b strings.Builder
+
+	for {
+		i := strings.Index(path, "{")
+		if i < 0 {
+			break
+		}
+
+		j := strings.Index(path[i:], "}")
+		if j < 0 {
+			return "", fmt.Errorf("unterminated path parameter in: %s", path)
+		}
+
+		name := path[i+1 : i+j]
+
+		if _, ok := uv[name]; !ok {
+			return "", fmt.Errorf("missing path parameter: %s", name)
+		}
+
+		v := uv.Get(name)
+
+		// escaping leaves dot segments alone so they would climb the path
+		if v == "." || v == ".." {
+			return "", fmt.Errorf("invalid path parameter %s: %q", name, v)
+		}
+
+		b.WriteString(path[:i])
+		b.WriteString(url.PathEscape(v))
+
+		path = path[i+j+1:]
+	}
+
+	b.WriteString(path)
+
+	return b.String(), nil
 }
 
 func (o *RequestOptions) Querystring() (string, error) {
-	u, err := marshalValues(o.Query)
+	u, err := marshalValues(o.Query, o.ArrayStyle)
 	if err != nil {
 		return "", err
 	}
@@ -50,7 +260,7 @@ func (o *RequestOptions) Content() (io.Reader, string, error) {
 		return o.Body, "application/octet-stream", nil
 	}
 
-	uv, err := marshalValues(o.Params)
+	uv, err := marshalValues(o.Params, ArrayDefault)
 	if err != nil {
 		return nil, "", err
 	}
@@ -89,42 +299,321 @@ func MarshalOptions(opts interface{}) (RequestOptions, error) {
 	ro := RequestOptions{
 		Headers: Headers{},
 		Params:  Params{},
+		Path:    Path{},
 		Query:   Query{},
 	}
 
-	v := reflect.ValueOf(opts)
-	t := v.Type()
+	if err := marshalStruct(&ro, reflect.ValueOf(opts)); err != nil {
+		return ro, err
+	}
+
+	return ro, nil
+}
+
+type optionField struct {
+	embedded bool
+	format   string
+	header   string
+	index    int
+	name     string
+	param    string
+	path     string
+	query    string
+	required bool
+	style    string
+}
+
+func (f optionField) tag(name string) string {
+	switch name {
+	case "param":
+		return f.param
+	case "query":
+		return f.query
+	default:
+		return ""
+	}
+}
+
+var optionFieldCache sync.Map
+
+func optionFields(t reflect.Type) []optionField {
+	if fs, ok := optionFieldCache.Load(t); ok {
+		return fs.([]optionField)
+	}
+
+	fs := make([]optionField, t.NumField())
 
-	for i := 0; i < t.NumField(); i++ {
+	for i := range fs {
 		f := t.Field(i)
 
-		if n := f.Tag.Get("header"); n != "" {
-			if u, ok := marshalValue(v.Field(i)); ok {
-				ro.Headers[n] = u
+		fs[i] = optionField{
+			embedded: f.Anonymous && f.Tag == "",
+			format:   f.Tag.Get("format"),
+			header:   f.Tag.Get("header"),
+			index:    i,
+			name:     f.Name,
+			param:    f.Tag.Get("param"),
+			path:     f.Tag.Get("path"),
+			query:    f.Tag.Get("query"),
+			required: f.Tag.Get("required") == "true",
+			style:    f.Tag.Get("style"),
+		}
+	}
+
+	optionFieldCache.Store(t, fs)
+
+	return fs
+}
+
+func marshalStruct(ro *RequestOptions, v reflect.Value) error {
+	for _, f := range optionFields(v.Type()) {
+		fv := v.Field(f.index)
+
+		if f.embedded {
+			ev := fv
+
+			if ev.Kind() == reflect.Ptr {
+				if ev.IsNil() {
+					continue
+				}
+				ev = ev.Elem()
+			}
+
+			if ev.Kind() == reflect.Struct {
+				if err := marshalStruct(ro, ev); err != nil {
+					return err
+				}
+				continue
+			}
+		}
+
+		if f.required && isMissing(fv) {
+			return fmt.Errorf("required option missing: %s", f.name)
+		}
+
+		if f.header != "" {
+			u, ok, err := marshalString(fv, f.format)
+			if err != nil {
+				return fmt.Errorf("%s: %w", f.name, err)
+			}
+			if ok {
+				ro.Headers[f.header] = u
+			}
+		}
+
+		if f.param != "" {
+			if err := marshalKeyed(ro.Params, fv, f.param, "param", f); err != nil {
+				return fmt.Errorf("%s: %w", f.name, err)
 			}
 		}
 
-		if n := f.Tag.Get("param"); n != "" {
-			if u, ok := marshalValue(v.Field(i)); ok {
-				ro.Params[n] = u
+		if f.path != "" {
+			u, ok, err := marshalString(fv, f.format)
+			if err != nil {
+				return fmt.Errorf("%s: %w", f.name, err)
+			}
+			if ok {
+				ro.Path[f.path] = u
 			}
 		}
 
-		if n := f.Tag.Get("query"); n != "" {
-			if u, ok := marshalValue(v.Field(i)); ok {
-				ro.Query[n] = u
+		if f.query != "" {
+			if err := marshalKeyed(ro.Query, fv, f.query, "query", f); err != nil {
+				return fmt.Errorf("%s: %w", f.name, err)
 			}
 		}
 	}
 
-	return ro, nil
+	return nil
 }
 
-func marshalValue(f reflect.Value) (string, bool) {
-	if f.IsNil() {
+func marshalKeyed(m map[string]interface{}, f reflect.Value, name, tagName string, of optionField) error {
+	if of.style == "deepObject" {
+		return marshalDeep(m, f, name, tagName)
+	}
+
+	if kv, ok := marshalMap(f, name, of.style); ok {
+		for k, v := range kv {
+			m[k] = v
+		}
+		return nil
+	}
+
+	u, ok, err := marshalField(f, of.format)
+	if err != nil {
+		return err
+	}
+
+	if ok {
+		m[name] = u
+	}
+
+	return nil
+}
+
+// marshalDeep encodes a nested struct as prefix[field][subfield] keys
+// using the tagName tags of the nested fields
+func marshalDeep(m map[string]interface{}, f reflect.Value, prefix, tagName string) error {
+	if f.Kind() == reflect.Ptr {
+		if f.IsNil() {
+			return nil
+		}
+		f = f.Elem()
+	}
+
+	if f.Kind() != reflect.Struct || f.Type() == reflect.TypeOf(time.Time{}) {
+		return marshalKeyed(m, f, prefix, tagName, optionField{})
+	}
+
+	for _, of := range optionFields(f.Type()) {
+		n := of.tag(tagName)
+		if n == "" {
+			continue
+		}
+
+		key := fmt.Sprintf("%s[%s]", prefix, n)
+
+		if err := marshalKeyed(m, f.Field(of.index), key, tagName, of); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// marshalMap expands a map[string]string into one key per entry, styles
+// are dot (name.key), bracket (name[key]) and flat (key)
+func marshalMap(f reflect.Value, name, style string) (map[string]string, bool) {
+	if style == "" {
+		return nil, false
+	}
+
+	if f.Kind() == reflect.Ptr {
+		if f.IsNil() {
+			return nil, false
+		}
+		f = f.Elem()
+	}
+
+	m, ok := f.Interface().(map[string]string)
+	if !ok || m == nil {
+		return nil, false
+	}
+
+	kv := map[string]string{}
+
+	for k, v := range m {
+		switch style {
+		case "bracket":
+			kv[fmt.Sprintf("%s[%s]", name, k)] = v
+		case "flat":
+			kv[k] = v
+		default:
+			kv[fmt.Sprintf("%s.%s", name, k)] = v
+		}
+	}
+
+	return kv, true
+}
+
+// isMissing only reports unset nillable fields, a zero value such as 0 or
+// "" may be exactly what the caller meant to send
+func isMissing(f reflect.Value) bool {
+	switch f.Kind() {
+	case reflect.Ptr, reflect.Interface, reflect.Map, reflect.Slice:
+		return f.IsNil()
+	default:
+		return false
+	}
+}
+
+// marshalField keeps slices as repeated values for params and query
+func marshalField(f reflect.Value, format string) (interface{}, bool, error) {
+	if ss, ok := marshalSlice(f); ok {
+		return ss, true, nil
+	}
+
+	return marshalString(f, format)
+}
+
+func marshalString(f reflect.Value, format string) (string, bool, error) {
+	if m, ok := optionMarshaler(f); ok {
+		s, err := m.MarshalOption()
+		if err != nil {
+			return "", false, err
+		}
+		return s, true, nil
+	}
+
+	if format != "" {
+		if s, ok := marshalTime(f, format); ok {
+			return s, true, nil
+		}
+	}
+
+	s, ok := marshalValue(f)
+
+	return s, ok, nil
+}
+
+func optionMarshaler(f reflect.Value) (OptionMarshaler, bool) {
+	switch f.Kind() {
+	case reflect.Ptr, reflect.Interface, reflect.Map, reflect.Slice:
+		if f.IsNil() {
+			return nil, false
+		}
+	}
+
+	if m, ok := f.Interface().(OptionMarshaler); ok {
+		return m, true
+	}
+
+	if f.Kind() == reflect.Ptr {
+		if m, ok := f.Elem().Interface().(OptionMarshaler); ok {
+			return m, true
+		}
+	}
+
+	return nil, false
+}
+
+func marshalTime(f reflect.Value, format string) (string, bool) {
+	if f.Kind() == reflect.Ptr {
+		if f.IsNil() {
+			return "", false
+		}
+		f = f.Elem()
+	}
+
+	t, ok := f.Interface().(time.Time)
+	if !ok {
 		return "", false
 	}
 
+	switch format {
+	case "rfc3339":
+		return t.Format(time.RFC3339), true
+	case "rfc3339nano":
+		return t.Format(time.RFC3339Nano), true
+	case "unix":
+		return fmt.Sprintf("%d", t.Unix()), true
+	case "unixms":
+		return fmt.Sprintf("%d", t.UnixNano()/int64(time.Millisecond)), true
+	case "date":
+		return t.Format("2006-01-02"), true
+	default:
+		return t.Format(format), true
+	}
+}
+
+func marshalValue(f reflect.Value) (string, bool) {
+	switch f.Kind() {
+	case reflect.Ptr, reflect.Interface, reflect.Map, reflect.Slice:
+		if f.IsNil() {
+			return "", false
+		}
+	}
+
 	v := f.Interface()
 
 	if f.Kind() == reflect.Ptr {
@@ -141,23 +630,84 @@ func marshalValue(f reflect.Value) (string, bool) {
 	case time.Duration:
 		return t.String(), true
 	case time.Time:
-		return t.Format("20060102.150405.000000000"), true
+		return t.Format(sortableTime), true
 	case []string:
 		return strings.Join(t, ","), true
+	case []int, []int64:
+		ss, _ := marshalSlice(f)
+		return strings.Join(ss, ","), true
 	case map[string]string:
 		uv := url.Values{}
 		for k, v := range t {
 			uv.Add(k, v)
 		}
 		return uv.Encode(), true
+	case fmt.Stringer:
+		return t.String(), true
+	default:
+		return marshalScalar(t)
+	}
+}
+
+// marshalScalar goes by kind so named types such as type ID int64 work
+func marshalScalar(v interface{}) (string, bool) {
+	rv := reflect.ValueOf(v)
+
+	if rv.Kind() == reflect.Ptr && !rv.IsNil() {
+		rv = rv.Elem()
+	}
+
+	switch rv.Kind() {
+	case reflect.Bool:
+		return strconv.FormatBool(rv.Bool()), true
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return strconv.FormatInt(rv.Int(), 10), true
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return strconv.FormatUint(rv.Uint(), 10), true
+	case reflect.Float32:
+		return strconv.FormatFloat(rv.Float(), 'f', -1, 32), true
+	case reflect.Float64:
+		return strconv.FormatFloat(rv.Float(), 'f', -1, 64), true
+	case reflect.String:
+		return rv.String(), true
 	default:
 		return "", false
 	}
+}
 
-	return "", true
+func marshalSlice(f reflect.Value) ([]string, bool) {
+	if f.Kind() == reflect.Ptr {
+		if f.IsNil() {
+			return nil, false
+		}
+		f = f.Elem()
+	}
+
+	if f.Kind() != reflect.Slice || f.IsNil() {
+		return nil, false
+	}
+
+	switch t := f.Interface().(type) {
+	case []string:
+		return t, true
+	case []int:
+		ss := make([]string, len(t))
+		for i, n := range t {
+			ss[i] = fmt.Sprintf("%d", n)
+		}
+		return ss, true
+	case []int64:
+		ss := make([]string, len(t))
+		for i, n := range t {
+			ss[i] = fmt.Sprintf("%d", n)
+		}
+		return ss, true
+	default:
+		return nil, false
+	}
 }
 
-func marshalValues(vv map[string]interface{}) (url.Values, error) {
+func marshalValues(vv map[string]interface{}, style ArrayStyle) (url.Values, error) {
 	u := url.Values{}
 
 	for k, v := range vv {
@@ -169,8 +719,13 @@ func marshalValues(vv map[string]interface{}) (url.Values, error) {
 		case string:
 			u.Set(k, t)
 		case []string:
-			for _, s := range t {
-				u.Add(k, s)
+			switch style {
+			case ArrayComma:
+				u.Set(k, strings.Join(t, ","))
+			case ArrayBrackets:
+				u[k+"[]"] = append([]string{}, t...)
+			default:
+				u[k] = append([]string{}, t...)
 			}
 		case time.Duration:
 			u.Set(k, t.String())
@@ -180,8 +735,24 @@ func marshalValues(vv map[string]interface{}) (url.Values, error) {
 				uv.Set(kk, vv)
 			}
 			u.Set(k, uv.Encode())
+		case OptionMarshaler:
+			s, err := t.MarshalOption()
+			if err != nil {
+				return nil, err
+			}
+			u.Set(k, s)
+		case time.Time:
+			u.Set(k, t.Format(time.RFC3339))
+		case *url.URL:
+			u.Set(k, t.String())
+		case fmt.Stringer:
+			u.Set(k, t.String())
 		default:
-			return nil, fmt.Errorf("unknown param type: %T", t)
+			s, ok := marshalScalar(t)
+			if !ok {
+				return nil, fmt.Errorf("unknown param type: %T", t)
+			}
+			u.Set(k, s)
 		}
 	}
 
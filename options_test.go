@@ -0,0 +1,115 @@
+package stdsdk_test
+
+import (
+	"encoding/json"
+	"net/url"
+	"testing"
+
+	"github.com/liamdawson/stdsdk"
+)
+
+type namedID int64
+
+type namedState string
+
+func TestMarshalOptionsNamedTypes(t *testing.T) {
+	id := namedID(7)
+
+	opts := struct {
+		ID     namedID    `query:"id"`
+		Ptr    *namedID   `query:"ptr"`
+		Nil    *namedID   `query:"nil"`
+		State  namedState `query:"state"`
+		Small  uint8      `header:"X-Small"`
+		Weight float32    `query:"weight"`
+	}{ID: 3, Ptr: &id, State: "open", Small: 2, Weight: 1.5}
+
+	ro, err := stdsdk.MarshalOptions(opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := map[string]interface{}{"id": "3", "ptr": "7", "state": "open", "weight": "1.5"}
+
+	if len(ro.Query) != len(want) {
+		t.Errorf("query = %v, want %v", ro.Query, want)
+	}
+
+	for k, v := range want {
+		if ro.Query[k] != v {
+			t.Errorf("query[%s] = %v, want %v", k, ro.Query[k], v)
+		}
+	}
+
+	if ro.Headers["X-Small"] != "2" {
+		t.Errorf("header = %q, want 2", ro.Headers["X-Small"])
+	}
+}
+
+func TestQueryNamedTypes(t *testing.T) {
+	c, err := stdsdk.New("https://example.org")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	u, err := c.URL("/", stdsdk.RequestOptions{Query: stdsdk.Query{
+		"id":    namedID(1),
+		"num":   json.Number("2.5"),
+		"state": namedState("closed"),
+	}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if want := (url.Values{"id": {"1"}, "num": {"2.5"}, "state": {"closed"}}).Encode(); u.RawQuery != want {
+		t.Errorf("query = %s, want %s", u.RawQuery, want)
+	}
+}
+
+func TestMarshalOptionsRequired(t *testing.T) {
+	type opts struct {
+		Count int      `query:"count" required:"true"`
+		Name  string   `path:"name" required:"true"`
+		Limit *int     `query:"limit" required:"true"`
+		Tags  []string `query:"tags"`
+	}
+
+	limit := 0
+
+	tests := []struct {
+		name string
+		opts opts
+		err  bool
+	}{
+		{"zero values are sent", opts{Limit: &limit}, false},
+		{"nil pointer is missing", opts{Count: 1, Name: "x"}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ro, err := stdsdk.MarshalOptions(tt.opts)
+			if (err != nil) != tt.err {
+				t.Fatalf("err = %v, want error %t", err, tt.err)
+			}
+			if err == nil && (ro.Query["count"] != "0" || ro.Query["limit"] != "0" || ro.Path["name"] != "") {
+				t.Errorf("unexpected options: %+v %+v", ro.Query, ro.Path)
+			}
+		})
+	}
+}
+
+func TestExpandPathDotSegments(t *testing.T) {
+	for _, v := range []string{".", ".."} {
+		opts := stdsdk.RequestOptions{Path: stdsdk.Path{"id": v}}
+
+		if _, err := opts.ExpandPath("/apps/{id}/env"); err == nil {
+			t.Errorf("%q: expected error", v)
+		}
+	}
+
+	opts := stdsdk.RequestOptions{Path: stdsdk.Path{"id": "a..b"}}
+
+	if p, err := opts.ExpandPath("/apps/{id}"); err != nil || p != "/apps/a..b" {
+		t.Errorf("path = %s, %v", p, err)
+	}
+}
@@ -0,0 +1,67 @@
+package stdsdk
+
+import (
+	"context"
+	"net/http"
+	"time"
+)
+
+const pingTimeout = 5 * time.Second
+
+type PingResult struct {
+	Header  http.Header
+	Latency time.Duration
+	Server  string
+	Status  int
+	Version string
+}
+
+// anyStatusKey makes HandleRequest return error statuses as responses, for
+// Ping and Warm which only care that the server answered
+type anyStatusKey struct{}
+
+// Ping sends GET HealthPath, or HEAD / when it is unset, and reports the
+// round trip latency along with any version headers the server returns. Any
+// response counts as reachable, whatever its status
+func (c *Client) Ping(ctx context.Context) (*PingResult, error) {
+	method, path := "HEAD", "/"
+
+	if c.HealthPath != "" {
+		method, path = "GET", c.HealthPath
+	}
+
+	cc := c.WithContext(context.WithValue(ctx, anyStatusKey{}, true))
+	cc.OpenAPI = nil
+
+	req, err := cc.Request(method, path, RequestOptions{Timeout: pingTimeout})
+	if err != nil {
+		return nil, err
+	}
+
+	started := c.clock().Now()
+
+	res, err := cc.HandleRequest(req)
+	if err != nil {
+		return nil, err
+	}
+
+	latency := c.clock().Now().Sub(started)
+
+	(&Response{Response: res}).Close()
+
+	pr := &PingResult{
+		Header:  res.Header,
+		Latency: latency,
+		Server:  res.Header.Get("Server"),
+		Status:  res.StatusCode,
+	}
+
+	for _, h := range []string{"X-Api-Version", "X-Version", "X-Server-Version"} {
+		if v := res.Header.Get(h); v != "" {
+			pr.Version = v
+			break
+		}
+	}
+
+	return pr, nil
+}
@@ -0,0 +1,53 @@
+package stdsdk
+
+import (
+	"bytes"
+	"io"
+	"sync"
+)
+
+const (
+	copyBufferSize  = 32 * 1024
+	maxPooledBuffer = 1024 * 1024
+)
+
+var bufferPool = sync.Pool{
+	New: func() interface{} { return new(bytes.Buffer) },
+}
+
+var copyPool = sync.Pool{
+	New: func() interface{} {
+		b := make([]byte, copyBufferSize)
+		return &b
+	},
+}
+
+func getBuffer() *bytes.Buffer {
+	return bufferPool.Get().(*bytes.Buffer)
+}
+
+// putBuffer returns b to the pool, oversized buffers are dropped so one
+// large response does not pin memory
+func putBuffer(b *bytes.Buffer) {
+	if b.Cap() > maxPooledBuffer {
+		return
+	}
+
+	b.Reset()
+	bufferPool.Put(b)
+}
+
+func getCopyBuffer() *[]byte {
+	return copyPool.Get().(*[]byte)
+}
+
+func putCopyBuffer(b *[]byte) {
+	copyPool.Put(b)
+}
+
+func copyBuffered(w io.Writer, r io.Reader) (int64, error) {
+	buf := getCopyBuffer()
+	defer putCopyBuffer(buf)
+
+	return io.CopyBuffer(w, r, *buf)
+}
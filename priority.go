@@ -0,0 +1,193 @@
+package stdsdk
+
+import (
+	"container/heap"
+	"context"
+	"fmt"
+	"sync"
+	"time"
+)
+
+type Priority int
+
+const (
+	PriorityBackground Priority = -10
+	PriorityNormal     Priority = 0
+	PriorityHigh       Priority = 10
+)
+
+type priorityKey struct{}
+
+func WithPriority(ctx context.Context, p Priority) context.Context {
+	return context.WithValue(ctx, priorityKey{}, p)
+}
+
+func RequestPriority(ctx context.Context) Priority {
+	p, _ := ctx.Value(priorityKey{}).(Priority)
+	return p
+}
+
+type QueueFullError struct {
+	Priority Priority
+	Queued   int
+}
+
+func (e *QueueFullError) Error() string {
+	return fmt.Sprintf("request queue full (%d queued, priority %d)", e.Queued, e.Priority)
+}
+
+// PriorityQueue is a Limiter that admits MaxInFlight requests and queues up
+// to MaxQueued more, highest priority first, zero MaxQueued leaves the queue
+// unbounded. When the queue is full a higher priority request evicts the
+// lowest queued one
+type PriorityQueue struct {
+	MaxInFlight int
+	MaxQueued   int
+
+	lock     sync.Mutex
+	inflight int
+	queue    priorityWaiters
+	seq      int64
+}
+
+type priorityWaiter struct {
+	index    int
+	priority Priority
+	ready    chan error
+	seq      int64
+}
+
+func (q *PriorityQueue) Acquire(ctx context.Context) (func(time.Duration, bool), error) {
+	p := RequestPriority(ctx)
+
+	q.lock.Lock()
+
+	if q.inflight < q.max() && q.queue.Len() == 0 {
+		q.inflight++
+		q.lock.Unlock()
+		return q.done(), nil
+	}
+
+	if q.MaxQueued > 0 && q.queue.Len() >= q.MaxQueued {
+		lowest := q.queue.lowest()
+
+		if lowest == nil || lowest.priority >= p {
+			n := q.queue.Len()
+			q.lock.Unlock()
+			return nil, &QueueFullError{Priority: p, Queued: n}
+		}
+
+		heap.Remove(&q.queue, lowest.index)
+		lowest.ready <- &QueueFullError{Priority: lowest.priority, Queued: q.queue.Len() + 1}
+	}
+
+	q.seq++
+
+	w := &priorityWaiter{priority: p, ready: make(chan error, 1), seq: q.seq}
+
+	heap.Push(&q.queue, w)
+
+	q.lock.Unlock()
+
+	select {
+	case err := <-w.ready:
+		if err != nil {
+			return nil, err
+		}
+		return q.done(), nil
+	case <-ctx.Done():
+		q.lock.Lock()
+		if w.index >= 0 {
+			heap.Remove(&q.queue, w.index)
+			q.lock.Unlock()
+			return nil, ctx.Err()
+		}
+		q.lock.Unlock()
+
+		// granted or evicted while giving up
+		if err := <-w.ready; err == nil {
+			q.release()
+		}
+
+		return nil, ctx.Err()
+	}
+}
+
+func (q *PriorityQueue) max() int {
+	if q.MaxInFlight <= 0 {
+		return 1
+	}
+
+	return q.MaxInFlight
+}
+
+func (q *PriorityQueue) done() func(time.Duration, bool) {
+	var once sync.Once
+
+	return func(time.Duration, bool) {
+		once.Do(q.release)
+	}
+}
+
+// release hands the slot straight to the next waiter so it cannot be taken
+// by a new arrival
+func (q *PriorityQueue) release() {
+	q.lock.Lock()
+	defer q.lock.Unlock()
+
+	if q.queue.Len() > 0 {
+		w := heap.Pop(&q.queue).(*priorityWaiter)
+		w.ready <- nil
+		return
+	}
+
+	q.inflight--
+}
+
+type priorityWaiters []*priorityWaiter
+
+func (pw priorityWaiters) Len() int {
+	return len(pw)
+}
+
+func (pw priorityWaiters) Less(i, j int) bool {
+	if pw[i].priority != pw[j].priority {
+		return pw[i].priority > pw[j].priority
+	}
+
+	return pw[i].seq < pw[j].seq
+}
+
+func (pw priorityWaiters) Swap(i, j int) {
+	pw[i], pw[j] = pw[j], pw[i]
+	pw[i].index = i
+	pw[j].index = j
+}
+
+func (pw *priorityWaiters) Push(x interface{}) {
+	w := x.(*priorityWaiter)
+	w.index = len(*pw)
+	*pw = append(*pw, w)
+}
+
+func (pw *priorityWaiters) Pop() interface{} {
+	old := *pw
+	w := old[len(old)-1]
+	old[len(old)-1] = nil
+	w.index = -1
+	*pw = old[:len(old)-1]
+	return w
+}
+
+// lowest is the newest of the lowest priority waiters
+func (pw priorityWaiters) lowest() *priorityWaiter {
+	var low *priorityWaiter
+
+	for _, w := range pw {
+		if low == nil || w.priority < low.priority || (w.priority == low.priority && w.seq > low.seq) {
+			low = w
+		}
+	}
+
+	return low
+}
@@ -0,0 +1,60 @@
+package stdsdk_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/liamdawson/stdsdk"
+)
+
+func TestPriorityQueueUnboundedByDefault(t *testing.T) {
+	q := &stdsdk.PriorityQueue{MaxInFlight: 1}
+
+	release, err := q.Acquire(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	admitted := make(chan error, 3)
+
+	for i := 0; i < 3; i++ {
+		go func() {
+			done, err := q.Acquire(context.Background())
+			if err == nil {
+				done(0, false)
+			}
+			admitted <- err
+		}()
+	}
+
+	time.Sleep(10 * time.Millisecond)
+	release(0, false)
+
+	for i := 0; i < 3; i++ {
+		if err := <-admitted; err != nil {
+			t.Errorf("queued request: %v", err)
+		}
+	}
+}
+
+func TestPriorityQueueFull(t *testing.T) {
+	q := &stdsdk.PriorityQueue{MaxInFlight: 1, MaxQueued: 1}
+
+	release, err := q.Acquire(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer release(0, false)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	go q.Acquire(ctx)
+
+	time.Sleep(10 * time.Millisecond)
+
+	if _, err := q.Acquire(context.Background()); err == nil {
+		t.Error("expected queue full error")
+	}
+}
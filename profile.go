@@ -0,0 +1,215 @@
+package stdsdk
+
+import (
+	"crypto/fips140"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Profile holds the settings for one environment, it is used by both
+// NewFromEnv and profile files
+type Profile struct {
+	ConnectTimeout     time.Duration     `yaml:"connect_timeout"`
+	DefaultHeaders     map[string]string `yaml:"headers"`
+	DefaultQuery       map[string]string `yaml:"query"`
+	Endpoint           string            `yaml:"endpoint"`
+	InsecureSkipVerify bool              `yaml:"insecure_skip_verify"`
+	Password           string            `yaml:"password"`
+	ServerName         string            `yaml:"tls_server_name"`
+	TLSProfile         string            `yaml:"tls_profile"`
+	Timeout            time.Duration     `yaml:"timeout"`
+	Token              string            `yaml:"token"`
+	UserAgent          string            `yaml:"user_agent"`
+	Username           string            `yaml:"username"`
+}
+
+// UnmarshalYAML reads timeouts as the environment does, so timeout: 30 is
+// thirty seconds
+func (p *Profile) UnmarshalYAML(n *yaml.Node) error {
+	type plain Profile
+
+	if n.Kind != yaml.MappingNode {
+		return n.Decode((*plain)(p))
+	}
+
+	rest := *n
+	rest.Content = nil
+
+	durations := map[string]*yaml.Node{}
+
+	for i := 0; i+1 < len(n.Content); i += 2 {
+		switch k := n.Content[i].Value; k {
+		case "connect_timeout", "timeout":
+			durations[k] = n.Content[i+1]
+		default:
+			rest.Content = append(rest.Content, n.Content[i], n.Content[i+1])
+		}
+	}
+
+	if err := rest.Decode((*plain)(p)); err != nil {
+		return err
+	}
+
+	for k, dst := range map[string]*time.Duration{"connect_timeout": &p.ConnectTimeout, "timeout": &p.Timeout} {
+		if v, ok := durations[k]; ok {
+			d, err := parseDuration(v.Value)
+			if err != nil {
+				return fmt.Errorf("invalid %s: %w", k, err)
+			}
+			*dst = d
+		}
+	}
+
+	return nil
+}
+
+// ProfileFile is the layout of ~/.config/stdsdk/config
+type ProfileFile struct {
+	Default  string              `yaml:"default"`
+	Profiles map[string]*Profile `yaml:"profiles"`
+}
+
+func DefaultProfilePath() (string, error) {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "", err
+	}
+
+	return filepath.Join(dir, "stdsdk", "config"), nil
+}
+
+func LoadProfiles(path string) (*ProfileFile, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var pf ProfileFile
+
+	if err := yaml.Unmarshal(data, &pf); err != nil {
+		return nil, fmt.Errorf("invalid profile file %s: %w", path, err)
+	}
+
+	return &pf, nil
+}
+
+// Profile looks up name, an empty name selects STDSDK_PROFILE, then the
+// file default, then the profile called default
+func (pf *ProfileFile) Profile(name string) (*Profile, error) {
+	if name == "" {
+		name = os.Getenv("STDSDK_PROFILE")
+	}
+
+	if name == "" {
+		name = pf.Default
+	}
+
+	if name == "" {
+		name = "default"
+	}
+
+	p, ok := pf.Profiles[name]
+	if !ok || p == nil {
+		return nil, fmt.Errorf("no such profile: %s (have %v)", name, pf.names())
+	}
+
+	return p, nil
+}
+
+func (pf *ProfileFile) names() []string {
+	names := make([]string, 0, len(pf.Profiles))
+
+	for k := range pf.Profiles {
+		names = append(names, k)
+	}
+
+	sort.Strings(names)
+
+	return names
+}
+
+// NewFromProfile loads the default profile file and configures a client
+// from the selected profile
+func NewFromProfile(name string) (*Client, error) {
+	path, err := DefaultProfilePath()
+	if err != nil {
+		return nil, err
+	}
+
+	pf, err := LoadProfiles(path)
+	if err != nil {
+		return nil, err
+	}
+
+	p, err := pf.Profile(name)
+	if err != nil {
+		return nil, err
+	}
+
+	return p.Client()
+}
+
+func (p *Profile) Client() (*Client, error) {
+	if p.Endpoint == "" {
+		return nil, fmt.Errorf("endpoint is required")
+	}
+
+	c, err := New(p.Endpoint)
+	if err != nil {
+		return nil, fmt.Errorf("invalid endpoint: %w", err)
+	}
+
+	if p.Token != "" {
+		c.Auth = TokenAuth{Source: staticToken(p.Token)}
+	}
+
+	c.Password = p.Password
+	c.Timeout = p.Timeout
+	c.UserAgent = p.UserAgent
+	c.Username = p.Username
+
+	for k, v := range p.DefaultHeaders {
+		if c.DefaultHeaders == nil {
+			c.DefaultHeaders = Headers{}
+		}
+		c.DefaultHeaders[k] = v
+	}
+
+	for k, v := range p.DefaultQuery {
+		if c.DefaultQuery == nil {
+			c.DefaultQuery = Query{}
+		}
+		c.DefaultQuery[k] = v
+	}
+
+	opts := TransportOptions{
+		ConnectTimeout:     p.ConnectTimeout,
+		InsecureSkipVerify: p.InsecureSkipVerify,
+		ServerName:         p.ServerName,
+	}
+
+	switch p.TLSProfile {
+	case "", "default":
+	case "fips":
+		if !fips140.Enabled() {
+			return nil, errFIPSDisabled
+		}
+		opts.TLSProfile = TLSFIPS
+	default:
+		return nil, fmt.Errorf("unknown tls profile: %s", p.TLSProfile)
+	}
+
+	// the shared default transport verifies so it is kept unless the
+	// profile changes something
+	if opts.ConnectTimeout > 0 || opts.ServerName != "" || opts.InsecureSkipVerify || opts.TLSProfile != TLSDefault {
+		c.Transport = NewTransport(opts)
+	}
+
+	return c, nil
+}
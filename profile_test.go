@@ -0,0 +1,53 @@
+package stdsdk_test
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/liamdawson/stdsdk"
+)
+
+func TestLoadProfilesTimeouts(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config")
+
+	data := `
+profiles:
+  prod:
+    endpoint: https://api.example.com
+    timeout: 30
+    connect_timeout: 1500ms
+`
+
+	if err := ioutil.WriteFile(path, []byte(data), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	pf, err := stdsdk.LoadProfiles(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	p := pf.Profiles["prod"]
+
+	if p.Endpoint != "https://api.example.com" {
+		t.Errorf("endpoint = %q", p.Endpoint)
+	}
+
+	if p.Timeout != 30*time.Second {
+		t.Errorf("timeout = %s, want 30s", p.Timeout)
+	}
+
+	if p.ConnectTimeout != 1500*time.Millisecond {
+		t.Errorf("connect timeout = %s, want 1.5s", p.ConnectTimeout)
+	}
+
+	if err := ioutil.WriteFile(path, []byte("profiles:\n  prod:\n    timeout: soon\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := stdsdk.LoadProfiles(path); err == nil {
+		t.Error("expected invalid timeout error")
+	}
+}
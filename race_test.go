@@ -0,0 +1,184 @@
+package stdsdk_test
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/gorilla/websocket"
+	"github.com/liamdawson/stdsdk"
+)
+
+// these tests exercise the concurrent use guarantee and are meant to be run
+// with go test -race
+
+const raceWorkers = 16
+
+func raceServer(t *testing.T) *httptest.Server {
+	up := websocket.Upgrader{}
+
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if websocket.IsWebSocketUpgrade(r) {
+			ws, err := up.Upgrade(w, r, nil)
+			if err != nil {
+				return
+			}
+			ws.WriteMessage(websocket.TextMessage, []byte(r.URL.Path))
+			ws.WriteMessage(websocket.BinaryMessage, nil)
+			ws.Close()
+			return
+		}
+
+		fmt.Fprintf(w, `{"path":%q,"query":%q,"header":%q}`, r.URL.Path, r.URL.RawQuery, r.Header.Get("X-Worker"))
+	}))
+
+	t.Cleanup(s.Close)
+
+	return s
+}
+
+func TestConcurrentRequests(t *testing.T) {
+	s := raceServer(t)
+
+	c, err := stdsdk.New(s.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	c.DefaultHeaders = stdsdk.Headers{"X-Shared": "1"}
+	c.DefaultQuery = stdsdk.Query{"shared": "1"}
+
+	opts := stdsdk.RequestOptions{Headers: stdsdk.Headers{"X-Base": "1"}, Query: stdsdk.Query{"base": "1"}}
+
+	var wg sync.WaitGroup
+
+	for i := 0; i < raceWorkers; i++ {
+		wg.Add(1)
+
+		go func(i int) {
+			defer wg.Done()
+
+			ro := opts.Merge(stdsdk.RequestOptions{
+				Headers: stdsdk.Headers{"X-Worker": fmt.Sprint(i)},
+				Path:    stdsdk.Path{"id": i},
+				Query:   stdsdk.Query{"worker": i},
+			})
+
+			var out struct {
+				Header string
+				Path   string
+				Query  string
+			}
+
+			if err := c.Get("/items/{id}", ro, &out); err != nil {
+				t.Error(err)
+				return
+			}
+
+			if out.Path != fmt.Sprintf("/items/%d", i) || out.Header != fmt.Sprint(i) || !strings.Contains(out.Query, fmt.Sprintf("worker=%d", i)) {
+				t.Errorf("worker %d got crossed response: %+v", i, out)
+			}
+		}(i)
+	}
+
+	wg.Wait()
+
+	if len(opts.Headers) != 1 || len(opts.Query) != 1 {
+		t.Errorf("shared options were modified: %+v", opts)
+	}
+}
+
+func TestConcurrentDerivedClients(t *testing.T) {
+	s := raceServer(t)
+
+	c, err := stdsdk.New(s.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	c.DefaultHeaders = stdsdk.Headers{"X-Shared": "1"}
+
+	var wg sync.WaitGroup
+
+	for i := 0; i < raceWorkers; i++ {
+		wg.Add(1)
+
+		go func(i int) {
+			defer wg.Done()
+
+			d := c.Sub("/tenants/t").Clone(stdsdk.WithHeader("X-Worker", fmt.Sprint(i)))
+
+			var out struct {
+				Header string
+				Path   string
+			}
+
+			if err := d.Get("/items", stdsdk.RequestOptions{}, &out); err != nil {
+				t.Error(err)
+				return
+			}
+
+			if out.Header != fmt.Sprint(i) || out.Path != "/tenants/t/items" {
+				t.Errorf("worker %d got crossed response: %+v", i, out)
+			}
+		}(i)
+	}
+
+	wg.Wait()
+
+	if len(c.DefaultHeaders) != 1 {
+		t.Errorf("parent headers were modified: %v", c.DefaultHeaders)
+	}
+
+	if st := c.Stats(); st.Requests != raceWorkers {
+		t.Errorf("requests = %d, want %d", st.Requests, raceWorkers)
+	}
+}
+
+func TestConcurrentWebsockets(t *testing.T) {
+	s := raceServer(t)
+
+	dialer := *websocket.DefaultDialer
+
+	c, err := stdsdk.New(s.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var wg sync.WaitGroup
+
+	for i := 0; i < raceWorkers; i++ {
+		wg.Add(1)
+
+		go func(i int) {
+			defer wg.Done()
+
+			r, err := c.Websocket(fmt.Sprintf("/ws/%d", i), stdsdk.RequestOptions{})
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			defer r.Close()
+
+			data, err := ioutil.ReadAll(r)
+			if err != nil {
+				t.Error(err)
+				return
+			}
+
+			if string(data) != fmt.Sprintf("/ws/%d", i) {
+				t.Errorf("worker %d got %q", i, data)
+			}
+		}(i)
+	}
+
+	wg.Wait()
+
+	if websocket.DefaultDialer.TLSClientConfig != dialer.TLSClientConfig || websocket.DefaultDialer.NetDialContext != nil {
+		t.Error("websocket.DefaultDialer was modified")
+	}
+}
@@ -0,0 +1,164 @@
+package stdsdk
+
+import (
+	"context"
+	"net/http"
+	"regexp"
+	"strings"
+)
+
+var (
+	redactUserinfo = regexp.MustCompile(`([a-zA-Z][a-zA-Z0-9+.-]*://)[^/@\s]+@`)
+	redactFields   = regexp.MustCompile(`(?i)("?(?:access_token|api_?key|client_secret|id_token|password|refresh_token|secret|token)"?\s*[:=]\s*"?)[^"&\s,}]+`)
+)
+
+// redactedError masks credentials in the message of err, Unwrap still
+// returns the original for errors.Is and errors.As
+type redactedError struct {
+	err error
+	msg string
+}
+
+func (e *redactedError) Error() string {
+	return e.msg
+}
+
+func (e *redactedError) Unwrap() error {
+	return e.err
+}
+
+// redactError removes userinfo, credential fields and the values of
+// sensitive headers in h from err, it is returned unchanged if nothing matched
+func (c *Client) redactError(err error, h http.Header) error {
+	msg := Redact(err.Error())
+
+	for _, secret := range c.secrets(h) {
+		msg = strings.Replace(msg, secret, redacted, -1)
+	}
+
+	if msg == err.Error() {
+		return err
+	}
+
+	return &redactedError{err: err, msg: msg}
+}
+
+// secrets lists credential values sent with a request, longest first so
+// a full header value is masked before the token inside it
+func (c *Client) secrets(h http.Header) []string {
+	s := []string{}
+
+	if c.Password != "" {
+		s = append(s, c.Password)
+	}
+
+	if cr, ok := c.Auth.(credentialed); ok {
+		// very short values would mask unrelated text
+		for _, v := range cr.credentials() {
+			if len(v) > 3 {
+				s = append(s, v)
+			}
+		}
+	}
+
+	for k, vs := range h {
+		if !c.sensitiveHeader(k) {
+			continue
+		}
+
+		for _, v := range vs {
+			s = append(s, v)
+			if i := strings.Index(v, " "); i > 0 && len(v[i+1:]) > 3 {
+				s = append(s, v[i+1:])
+			}
+		}
+	}
+
+	for i := 1; i < len(s); i++ {
+		for j := i; j > 0 && len(s[j]) > len(s[j-1]); j-- {
+			s[j], s[j-1] = s[j-1], s[j]
+		}
+	}
+
+	return s
+}
+
+type redactKey struct{}
+
+// withSensitive carries the client redaction settings to debug output, har
+// recordings and cassettes, which only see the request
+func (c *Client) withSensitive(req *http.Request) *http.Request {
+	return req.WithContext(context.WithValue(req.Context(), redactKey{}, c))
+}
+
+func sensitiveFor(req *http.Request) func(string) bool {
+	if req != nil {
+		if c, ok := req.Context().Value(redactKey{}).(*Client); ok {
+			return c.sensitiveHeader
+		}
+	}
+
+	return func(k string) bool {
+		return sensitiveHeaders[http.CanonicalHeaderKey(k)]
+	}
+}
+
+// redactRequestURL is redactURL plus the credentials of the client sending req
+func redactRequestURL(req *http.Request) string {
+	s := redactURL(req.URL.String())
+
+	if c, ok := req.Context().Value(redactKey{}).(*Client); ok {
+		for _, secret := range c.secrets(nil) {
+			s = strings.Replace(s, secret, redacted, -1)
+		}
+	}
+
+	return s
+}
+
+// redactParam masks a single query value of req
+func redactParam(req *http.Request, k, v string) string {
+	if sensitiveQuery(k) || secretValue(req, v) {
+		return redacted
+	}
+
+	return v
+}
+
+// secretValue reports whether v is a credential of the client sending req
+func secretValue(req *http.Request, v string) bool {
+	if c, ok := req.Context().Value(redactKey{}).(*Client); ok {
+		for _, secret := range c.secrets(nil) {
+			if v == secret {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+func (c *Client) sensitiveHeader(k string) bool {
+	k = http.CanonicalHeaderKey(k)
+
+	if sensitiveHeaders[k] {
+		return true
+	}
+
+	for _, sh := range c.SensitiveHeaders {
+		if http.CanonicalHeaderKey(sh) == k {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Redact masks url userinfo and common credential fields such as
+// access_token or password in s
+func Redact(s string) string {
+	s = redactUserinfo.ReplaceAllString(s, "${1}"+redacted+"@")
+	s = redactFields.ReplaceAllString(s, "${1}"+redacted)
+
+	return s
+}
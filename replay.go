@@ -0,0 +1,205 @@
+package stdsdk
+
+import (
+	"bytes"
+	"context"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"sort"
+)
+
+// CapturedRequest is a fully prepared request that can be persisted as json
+// and replayed later, possibly by another process
+type CapturedRequest struct {
+	Body   []byte      `json:"body,omitempty"`
+	Header http.Header `json:"header"`
+	Method string      `json:"method"`
+	URL    string      `json:"url"`
+}
+
+// CaptureRequest buffers the body of req, leaving req itself usable. Credential
+// headers are dropped and credential query values replaced with a marker since
+// Replay applies them afresh
+func CaptureRequest(req *http.Request) (*CapturedRequest, error) {
+	sensitive := sensitiveFor(req)
+
+	cr := &CapturedRequest{
+		Header: http.Header{},
+		Method: req.Method,
+	}
+
+	for k, vs := range req.Header {
+		if !sensitive(k) {
+			cr.Header[k] = append([]string(nil), vs...)
+		}
+	}
+
+	u := *req.URL
+	q := url.Values{}
+
+	for k, vs := range u.Query() {
+		for _, v := range vs {
+			if credentialQuery(k) || secretValue(req, v) {
+				v = redacted
+			}
+			q.Add(k, v)
+		}
+	}
+
+	u.User = nil
+	u.RawQuery = q.Encode()
+	cr.URL = u.String()
+
+	if req.Body != nil && req.Body != http.NoBody {
+		data, err := ioutil.ReadAll(req.Body)
+		if err != nil {
+			return nil, err
+		}
+
+		req.Body.Close()
+		req.Body = ioutil.NopCloser(bytes.NewReader(data))
+
+		cr.Body = data
+	}
+
+	return cr, nil
+}
+
+// Capture prepares a request exactly as the verb helpers would without sending it
+func (c *Client) Capture(method, path string, opts RequestOptions) (*CapturedRequest, error) {
+	req, err := c.Request(method, path, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	return CaptureRequest(c.withSensitive(req))
+}
+
+func (cr *CapturedRequest) Request(ctx context.Context) (*http.Request, error) {
+	req, err := http.NewRequestWithContext(ctx, cr.Method, cr.URL, bytes.NewReader(cr.Body))
+	if err != nil {
+		return nil, err
+	}
+
+	if len(cr.Body) == 0 {
+		req.Body = http.NoBody
+		req.GetBody = nil
+	}
+
+	req.Header = cr.Header.Clone()
+	if req.Header == nil {
+		req.Header = http.Header{}
+	}
+
+	// redacted credentials are left for Replay to restore
+	q := req.URL.Query()
+
+	if ks := redactedParams(q); len(ks) > 0 {
+		for _, k := range ks {
+			q.Del(k)
+		}
+		req.URL.RawQuery = q.Encode()
+	}
+
+	return req, nil
+}
+
+func redactedParams(q url.Values) []string {
+	ks := []string{}
+
+	for k, vs := range q {
+		for _, v := range vs {
+			if v == redacted {
+				ks = append(ks, k)
+				break
+			}
+		}
+	}
+
+	sort.Strings(ks)
+
+	return ks
+}
+
+// Replay sends cr again, credentials are reapplied since captured ones may have expired
+func (c *Client) Replay(cr *CapturedRequest) (*Response, error) {
+	u, err := url.Parse(cr.URL)
+	if err != nil {
+		return nil, err
+	}
+
+	req, err := cr.Request(withClock(c.ctx, c.ServerClock()))
+	if err != nil {
+		return nil, err
+	}
+
+	if err := c.restore(req, redactedParams(u.Query())); err != nil {
+		return nil, err
+	}
+
+	res, err := c.HandleRequest(req)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Response{Response: res}, nil
+}
+
+// restore reapplies what Capture dropped: default headers including basic
+// auth, default query values for the redacted params, Auth and Prepare
+func (c *Client) restore(req *http.Request, params []string) error {
+	h := c.headers()
+
+	for k := range h {
+		if _, ok := req.Header[k]; !ok {
+			req.Header.Set(k, h.Get(k))
+		}
+	}
+
+	if len(params) > 0 {
+		opts := RequestOptions{ArrayStyle: c.ArrayStyle, Query: c.DefaultQuery}
+
+		qs, err := opts.Querystring()
+		if err != nil {
+			return err
+		}
+
+		dq, err := url.ParseQuery(qs)
+		if err != nil {
+			return err
+		}
+
+		q := req.URL.Query()
+
+		for _, k := range params {
+			if vs, ok := dq[k]; ok {
+				q[k] = vs
+			}
+		}
+
+		req.URL.RawQuery = q.Encode()
+	}
+
+	if c.Auth != nil {
+		ctx := req.Context()
+
+		if c.Timeout > 0 {
+			var cancel context.CancelFunc
+			ctx, cancel = context.WithTimeout(ctx, c.Timeout)
+			defer cancel()
+		}
+
+		if err := c.Auth.Apply(ctx, req); err != nil {
+			return err
+		}
+	}
+
+	for _, fn := range c.Prepare {
+		if err := fn(req); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,96 @@
+package stdsdk_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/liamdawson/stdsdk"
+)
+
+func TestCaptureKeepsFunctionalParams(t *testing.T) {
+	c, err := stdsdk.New("https://api.example.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	c.Auth = stdsdk.APIKey{Key: "query-secret", Name: "k", Placement: stdsdk.APIKeyQuery}
+
+	q := stdsdk.Query{"access_token": "tok", "limit": 5, "page_token": "abc", "sort_key": "name"}
+
+	cr, err := c.Capture("GET", "/apps", stdsdk.RequestOptions{Query: q})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := json.Marshal(cr)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, secret := range []string{"query-secret", "tok&", "tok\""} {
+		if strings.Contains(string(data), secret) {
+			t.Errorf("capture contains %q: %s", secret, data)
+		}
+	}
+
+	u, err := url.Parse(cr.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := url.Values{"access_token": {"[REDACTED]"}, "k": {"[REDACTED]"}, "limit": {"5"}, "page_token": {"abc"}, "sort_key": {"name"}}
+
+	if got := u.Query(); got.Encode() != want.Encode() {
+		t.Errorf("query = %s, want %s", got.Encode(), want.Encode())
+	}
+}
+
+func TestReplayRestoresCredentials(t *testing.T) {
+	var got *http.Request
+
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = r
+	}))
+	defer ts.Close()
+
+	c, err := stdsdk.New(ts.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	c.Username = "user"
+	c.Password = "pass"
+	c.DefaultHeaders = stdsdk.Headers{"X-Api-Key": "header-secret"}
+	c.DefaultQuery = stdsdk.Query{"token": "query-secret"}
+
+	cr, err := c.Capture("GET", "/apps", stdsdk.RequestOptions{Query: stdsdk.Query{"page_token": "abc"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, k := range []string{"Authorization", "X-Api-Key"} {
+		if v := cr.Header.Get(k); v != "" {
+			t.Errorf("captured %s = %q", k, v)
+		}
+	}
+
+	if _, err := c.Replay(cr); err != nil {
+		t.Fatal(err)
+	}
+
+	if u, p, ok := got.BasicAuth(); !ok || u != "user" || p != "pass" {
+		t.Errorf("basic auth = %q %q %t", u, p, ok)
+	}
+
+	if v := got.Header.Get("X-Api-Key"); v != "header-secret" {
+		t.Errorf("api key header = %q", v)
+	}
+
+	if want := (url.Values{"page_token": {"abc"}, "token": {"query-secret"}}).Encode(); got.URL.RawQuery != want {
+		t.Errorf("query = %s, want %s", got.URL.RawQuery, want)
+	}
+}
@@ -0,0 +1,60 @@
+package stdsdk
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+	"syscall"
+)
+
+const defaultConnRetries = 2
+
+// negative ConnRetries disables retrying dropped connections
+func (c *Client) connRetries() int {
+	switch {
+	case c.ConnRetries < 0:
+		return 0
+	case c.ConnRetries == 0:
+		return defaultConnRetries
+	default:
+		return c.ConnRetries
+	}
+}
+
+// connReset reports whether err is a recycled or reset connection and req
+// can be sent again. These errors can arrive after the server acted so only
+// idempotent requests qualify, http.Transport already resends others when
+// nothing was written
+func connReset(req *http.Request, err error) bool {
+	if req.Context().Err() != nil || !idempotent(req) {
+		return false
+	}
+
+	if req.Body != nil && req.Body != http.NoBody && req.GetBody == nil {
+		return false
+	}
+
+	if errors.Is(err, syscall.ECONNRESET) || errors.Is(err, syscall.EPIPE) || errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+		return true
+	}
+
+	msg := err.Error()
+
+	for _, s := range []string{"connection reset by peer", "broken pipe", "server sent GOAWAY", "http2: client connection lost", "server closed idle connection"} {
+		if strings.Contains(msg, s) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func idempotent(req *http.Request) bool {
+	switch req.Method {
+	case "GET", "HEAD", "OPTIONS", "TRACE", "PUT", "DELETE":
+		return true
+	}
+
+	return req.Header.Get("Idempotency-Key") != ""
+}
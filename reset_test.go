@@ -0,0 +1,79 @@
+package stdsdk_test
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+
+	"github.com/liamdawson/stdsdk"
+)
+
+// dropFirst handles the request and then drops the connection without a
+// response, later requests succeed
+func dropFirst(hits *int32) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(hits, 1) == 1 {
+			conn, _, err := w.(http.Hijacker).Hijack()
+			if err == nil {
+				conn.Close()
+			}
+			return
+		}
+
+		w.Write([]byte("ok"))
+	})
+}
+
+func TestConnResetRetry(t *testing.T) {
+	tests := []struct {
+		method string
+		key    string
+		hits   int32
+		err    bool
+	}{
+		{"POST", "", 1, true},
+		{"POST", "k1", 2, false},
+		{"PUT", "", 2, false},
+		{"DELETE", "", 2, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.method+tt.key, func(t *testing.T) {
+			var hits int32
+
+			ts := httptest.NewServer(dropFirst(&hits))
+			defer ts.Close()
+
+			c, err := stdsdk.New(ts.URL)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			opts := stdsdk.RequestOptions{Body: bytes.NewReader([]byte("body"))}
+
+			if tt.key != "" {
+				opts.Headers = stdsdk.Headers{"Idempotency-Key": tt.key}
+			}
+
+			req, err := c.Request(tt.method, "/", opts)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			res, err := c.HandleRequest(req)
+			if err == nil {
+				res.Body.Close()
+			}
+
+			if (err != nil) != tt.err {
+				t.Errorf("err = %v, want error %t", err, tt.err)
+			}
+
+			if n := atomic.LoadInt32(&hits); n != tt.hits {
+				t.Errorf("server hits = %d, want %d", n, tt.hits)
+			}
+		})
+	}
+}
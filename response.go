@@ -0,0 +1,118 @@
+package stdsdk
+
+import (
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const drainLimit = 256 * 1024
+
+type Response struct {
+	*http.Response
+}
+
+// Close drains a bounded amount of the body so the connection can be reused
+func (r *Response) Close() error {
+	copyBuffered(ioutil.Discard, io.LimitReader(r.Body, drainLimit))
+	return r.Body.Close()
+}
+
+func (r *Response) Read(p []byte) (int, error) {
+	return r.Body.Read(p)
+}
+
+// BufferStats reports on the StreamBuffer requested for this response
+func (r *Response) BufferStats() (BufferStats, bool) {
+	if br, ok := r.Body.(*BufferedReader); ok {
+		return br.Stats(), true
+	}
+
+	return BufferStats{}, false
+}
+
+// Trailers is only populated once the body has been read to EOF
+func (r *Response) Trailers() http.Header {
+	return r.Trailer
+}
+
+type Metadata struct {
+	ContentLength int64
+	ContentType   string
+	ETag          string
+	Header        http.Header
+	LastModified  time.Time
+	StatusCode    int
+}
+
+func responseMetadata(res *http.Response) *Metadata {
+	m := &Metadata{
+		ContentLength: res.ContentLength,
+		ContentType:   res.Header.Get("Content-Type"),
+		ETag:          res.Header.Get("ETag"),
+		Header:        res.Header,
+		StatusCode:    res.StatusCode,
+	}
+
+	if lm := res.Header.Get("Last-Modified"); lm != "" {
+		if t, err := http.ParseTime(lm); err == nil {
+			m.LastModified = t
+		}
+	}
+
+	return m
+}
+
+type Capabilities struct {
+	Allow         []string
+	AllowHeaders  []string
+	AllowMethods  []string
+	AllowOrigin   string
+	ExposeHeaders []string
+	Header        http.Header
+	MaxAge        time.Duration
+}
+
+func (c *Capabilities) Allows(method string) bool {
+	for _, m := range append(c.Allow, c.AllowMethods...) {
+		if strings.EqualFold(m, method) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func responseCapabilities(res *http.Response) *Capabilities {
+	c := &Capabilities{
+		Allow:         headerList(res.Header, "Allow"),
+		AllowHeaders:  headerList(res.Header, "Access-Control-Allow-Headers"),
+		AllowMethods:  headerList(res.Header, "Access-Control-Allow-Methods"),
+		AllowOrigin:   res.Header.Get("Access-Control-Allow-Origin"),
+		ExposeHeaders: headerList(res.Header, "Access-Control-Expose-Headers"),
+		Header:        res.Header,
+	}
+
+	if n, err := strconv.Atoi(res.Header.Get("Access-Control-Max-Age")); err == nil {
+		c.MaxAge = time.Duration(n) * time.Second
+	}
+
+	return c
+}
+
+func headerList(h http.Header, key string) []string {
+	list := []string{}
+
+	for _, v := range h.Values(key) {
+		for _, s := range strings.Split(v, ",") {
+			if s = strings.TrimSpace(s); s != "" {
+				list = append(list, s)
+			}
+		}
+	}
+
+	return list
+}
@@ -0,0 +1,200 @@
+package stdsdk
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"sync"
+	"time"
+
+	"golang.org/x/crypto/ocsp"
+)
+
+type RevocationChecker interface {
+	CheckRevocation(cs tls.ConnectionState) error
+}
+
+// clockedRevocation is implemented by checkers that fall back to the
+// transport clock when they have none of their own
+type clockedRevocation interface {
+	checkRevocation(cs tls.ConnectionState, fallback Clock) error
+}
+
+type RevocationFunc func(cs tls.ConnectionState) error
+
+func (fn RevocationFunc) CheckRevocation(cs tls.ConnectionState) error {
+	return fn(cs)
+}
+
+type MultiRevocation []RevocationChecker
+
+func (m MultiRevocation) CheckRevocation(cs tls.ConnectionState) error {
+	for _, rc := range m {
+		if err := rc.CheckRevocation(cs); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// validates the stapled ocsp response, servers that do not staple pass unless Required
+type OCSPStapling struct {
+	Clock    Clock
+	Required bool
+}
+
+func (o OCSPStapling) CheckRevocation(cs tls.ConnectionState) error {
+	return o.checkRevocation(cs, nil)
+}
+
+func (o OCSPStapling) checkRevocation(cs tls.ConnectionState, fallback Clock) error {
+	if len(cs.OCSPResponse) == 0 {
+		if o.Required {
+			return fmt.Errorf("revocation: no stapled ocsp response")
+		}
+		return nil
+	}
+
+	leaf, issuer, err := peerChain(cs)
+	if err != nil {
+		return err
+	}
+
+	res, err := ocsp.ParseResponseForCert(cs.OCSPResponse, leaf, issuer)
+	if err != nil {
+		return fmt.Errorf("revocation: invalid ocsp response: %w", err)
+	}
+
+	clock := orClock(o.Clock, fallback)
+
+	if !res.NextUpdate.IsZero() && clock.Now().After(res.NextUpdate) {
+		return fmt.Errorf("revocation: stale ocsp response")
+	}
+
+	switch res.Status {
+	case ocsp.Good:
+		return nil
+	case ocsp.Revoked:
+		return fmt.Errorf("revocation: certificate %s revoked at %s", leaf.SerialNumber, res.RevokedAt.Format(time.RFC3339))
+	default:
+		if o.Required {
+			return fmt.Errorf("revocation: ocsp status unknown for certificate %s", leaf.SerialNumber)
+		}
+		return nil
+	}
+}
+
+// fetches crls from the leaf distribution points and caches them until their next update
+type CRLChecker struct {
+	Client *http.Client
+	Clock  Clock
+
+	lock  sync.Mutex
+	cache map[string]cachedCRL
+}
+
+// crls without a next update are refetched after this long
+const crlDefaultTTL = time.Hour
+
+type cachedCRL struct {
+	crl     *x509.RevocationList
+	expires time.Time
+}
+
+func (c *CRLChecker) CheckRevocation(cs tls.ConnectionState) error {
+	return c.checkRevocation(cs, nil)
+}
+
+func (c *CRLChecker) checkRevocation(cs tls.ConnectionState, fallback Clock) error {
+	leaf, issuer, err := peerChain(cs)
+	if err != nil {
+		return err
+	}
+
+	for _, u := range leaf.CRLDistributionPoints {
+		crl, err := c.fetch(u, issuer, orClock(c.Clock, fallback))
+		if err != nil {
+			return err
+		}
+
+		for _, e := range crl.RevokedCertificateEntries {
+			if e.SerialNumber.Cmp(leaf.SerialNumber) == 0 {
+				return fmt.Errorf("revocation: certificate %s revoked at %s", leaf.SerialNumber, e.RevocationTime.Format(time.RFC3339))
+			}
+		}
+	}
+
+	return nil
+}
+
+func (c *CRLChecker) fetch(u string, issuer *x509.Certificate, clock Clock) (*x509.RevocationList, error) {
+	now := clock.Now()
+
+	c.lock.Lock()
+	cached, ok := c.cache[u]
+	c.lock.Unlock()
+
+	if ok && now.Before(cached.expires) {
+		return cached.crl, nil
+	}
+
+	hc := c.Client
+	if hc == nil {
+		hc = &http.Client{Timeout: 10 * time.Second}
+	}
+
+	res, err := hc.Get(u)
+	if err != nil {
+		return nil, fmt.Errorf("revocation: could not fetch crl %s: %w", u, err)
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("revocation: could not fetch crl %s: %s", u, res.Status)
+	}
+
+	data, err := ioutil.ReadAll(res.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	crl, err := x509.ParseRevocationList(data)
+	if err != nil {
+		return nil, fmt.Errorf("revocation: invalid crl %s: %w", u, err)
+	}
+
+	if err := crl.CheckSignatureFrom(issuer); err != nil {
+		return nil, fmt.Errorf("revocation: invalid crl signature %s: %w", u, err)
+	}
+
+	expires := crl.NextUpdate
+	if expires.IsZero() {
+		expires = now.Add(crlDefaultTTL)
+	}
+
+	c.lock.Lock()
+	if c.cache == nil {
+		c.cache = map[string]cachedCRL{}
+	}
+	c.cache[u] = cachedCRL{crl: crl, expires: expires}
+	c.lock.Unlock()
+
+	return crl, nil
+}
+
+func peerChain(cs tls.ConnectionState) (*x509.Certificate, *x509.Certificate, error) {
+	chain := cs.PeerCertificates
+
+	if len(cs.VerifiedChains) > 0 {
+		chain = cs.VerifiedChains[0]
+	}
+
+	if len(chain) < 2 {
+		return nil, nil, fmt.Errorf("revocation: peer did not present an issuer certificate")
+	}
+
+	return chain[0], chain[1], nil
+}
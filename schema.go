@@ -0,0 +1,533 @@
+package stdsdk
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"math"
+	"net/http"
+	"path"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"unicode/utf8"
+)
+
+// Schema validates json documents against the commonly used subset of json
+// schema: type, enum, const, properties, required, additionalProperties,
+// items, length and range bounds, pattern, allOf/anyOf/oneOf/not and local
+// $ref pointers
+type Schema struct {
+	root interface{}
+
+	lock     sync.Mutex
+	patterns map[string]*regexp.Regexp
+}
+
+type SchemaError struct {
+	Message string
+	Path    string
+}
+
+func (e SchemaError) Error() string {
+	return fmt.Sprintf("%s: %s", e.Path, e.Message)
+}
+
+type SchemaErrors []SchemaError
+
+func (e SchemaErrors) Error() string {
+	msgs := make([]string, len(e))
+
+	for i, err := range e {
+		msgs[i] = err.Error()
+	}
+
+	return fmt.Sprintf("response does not match schema: %s", strings.Join(msgs, "; "))
+}
+
+func ParseSchema(data []byte) (*Schema, error) {
+	var root interface{}
+
+	if err := json.Unmarshal(data, &root); err != nil {
+		return nil, fmt.Errorf("invalid schema: %w", err)
+	}
+
+	switch root.(type) {
+	case map[string]interface{}, bool:
+	default:
+		return nil, fmt.Errorf("invalid schema: must be an object or boolean")
+	}
+
+	return &Schema{root: root}, nil
+}
+
+func MustParseSchema(data []byte) *Schema {
+	s, err := ParseSchema(data)
+	if err != nil {
+		panic(err)
+	}
+
+	return s
+}
+
+func (s *Schema) Validate(data []byte) error {
+	return s.validate(data, false)
+}
+
+// strict rejects object properties not declared by the schema unless it
+// sets additionalProperties explicitly
+func (s *Schema) validate(data []byte, strict bool) error {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+
+	var doc interface{}
+
+	if err := dec.Decode(&doc); err != nil {
+		return err
+	}
+
+	if errs := s.check(s.root, doc, "$", strict); len(errs) > 0 {
+		return errs
+	}
+
+	return nil
+}
+
+// check validates doc against node, a subschema that may $ref into root
+func (s *Schema) check(node, doc interface{}, at string, strict bool) SchemaErrors {
+	v := &schemaValidator{schema: s, strict: strict}
+	v.check(node, doc, at)
+	return v.errs
+}
+
+func (s *Schema) pattern(p string) (*regexp.Regexp, error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	if re, ok := s.patterns[p]; ok {
+		return re, nil
+	}
+
+	re, err := regexp.Compile(p)
+	if err != nil {
+		return nil, err
+	}
+
+	if s.patterns == nil {
+		s.patterns = map[string]*regexp.Regexp{}
+	}
+
+	s.patterns[p] = re
+
+	return re, nil
+}
+
+type schemaValidator struct {
+	errs   SchemaErrors
+	schema *Schema
+	strict bool
+
+	// refs followed at a document location, a repeat means a cycle
+	refs map[string]bool
+}
+
+func (v *schemaValidator) fail(at, format string, args ...interface{}) {
+	v.errs = append(v.errs, SchemaError{Message: fmt.Sprintf(format, args...), Path: at})
+}
+
+// matches reports whether doc satisfies sch without recording errors
+func (v *schemaValidator) matches(sch, doc interface{}, at string) bool {
+	sub := &schemaValidator{refs: v.refs, schema: v.schema, strict: v.strict}
+	sub.check(sch, doc, at)
+	return len(sub.errs) == 0
+}
+
+func (v *schemaValidator) check(sch, doc interface{}, at string) {
+	switch t := sch.(type) {
+	case bool:
+		if !t {
+			v.fail(at, "not allowed")
+		}
+		return
+	case map[string]interface{}:
+	default:
+		return
+	}
+
+	s := sch.(map[string]interface{})
+
+	if ref, ok := s["$ref"].(string); ok {
+		key := ref + " " + at
+
+		if v.refs[key] {
+			v.fail(at, "circular $ref: %s", ref)
+			return
+		}
+
+		target, err := v.schema.resolve(ref)
+		if err != nil {
+			v.fail(at, "%s", err)
+			return
+		}
+
+		if v.refs == nil {
+			v.refs = map[string]bool{}
+		}
+
+		v.refs[key] = true
+		v.check(target, doc, at)
+		delete(v.refs, key)
+		return
+	}
+
+	if t, ok := s["type"]; ok && !v.checkType(t, doc) {
+		v.fail(at, "expected %s, got %s", typeNames(t), jsonType(doc))
+		return
+	}
+
+	if e, ok := s["enum"].([]interface{}); ok {
+		found := false
+		for _, ev := range e {
+			if jsonEqual(ev, doc) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			v.fail(at, "value not in enum")
+		}
+	}
+
+	if c, ok := s["const"]; ok && !jsonEqual(c, doc) {
+		v.fail(at, "value does not match const")
+	}
+
+	if all, ok := s["allOf"].([]interface{}); ok {
+		for _, sub := range all {
+			v.check(sub, doc, at)
+		}
+	}
+
+	if anyOf, ok := s["anyOf"].([]interface{}); ok {
+		matched := false
+		for _, sub := range anyOf {
+			if v.matches(sub, doc, at) {
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			v.fail(at, "value does not match any schema in anyOf")
+		}
+	}
+
+	if one, ok := s["oneOf"].([]interface{}); ok {
+		n := 0
+		for _, sub := range one {
+			if v.matches(sub, doc, at) {
+				n++
+			}
+		}
+		if n != 1 {
+			v.fail(at, "value matches %d schemas in oneOf", n)
+		}
+	}
+
+	if not, ok := s["not"]; ok && v.matches(not, doc, at) {
+		v.fail(at, "value matches schema in not")
+	}
+
+	switch d := doc.(type) {
+	case map[string]interface{}:
+		v.checkObject(s, d, at)
+	case []interface{}:
+		v.checkArray(s, d, at)
+	case string:
+		v.checkString(s, d, at)
+	case json.Number:
+		v.checkNumber(s, d, at)
+	}
+}
+
+func (v *schemaValidator) checkObject(s map[string]interface{}, doc map[string]interface{}, at string) {
+	if req, ok := s["required"].([]interface{}); ok {
+		for _, r := range req {
+			if name, ok := r.(string); ok {
+				if _, ok := doc[name]; !ok {
+					v.fail(at, "missing required property: %s", name)
+				}
+			}
+		}
+	}
+
+	props, _ := s["properties"].(map[string]interface{})
+
+	additional, hasAdditional := s["additionalProperties"]
+
+	keys := make([]string, 0, len(doc))
+	for k := range doc {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	for _, k := range keys {
+		if ps, ok := props[k]; ok {
+			v.check(ps, doc[k], at+"."+k)
+			continue
+		}
+
+		switch {
+		case hasAdditional:
+			if b, ok := additional.(bool); ok && !b {
+				v.fail(at, "unexpected property: %s", k)
+			} else {
+				v.check(additional, doc[k], at+"."+k)
+			}
+		case v.strict && props != nil:
+			v.fail(at, "unexpected property: %s", k)
+		}
+	}
+}
+
+func (v *schemaValidator) checkArray(s map[string]interface{}, doc []interface{}, at string) {
+	if n, ok := schemaNumber(s, "minItems"); ok && float64(len(doc)) < n {
+		v.fail(at, "expected at least %v items, got %d", n, len(doc))
+	}
+
+	if n, ok := schemaNumber(s, "maxItems"); ok && float64(len(doc)) > n {
+		v.fail(at, "expected at most %v items, got %d", n, len(doc))
+	}
+
+	if items, ok := s["items"]; ok {
+		for i, item := range doc {
+			v.check(items, item, fmt.Sprintf("%s[%d]", at, i))
+		}
+	}
+}
+
+func (v *schemaValidator) checkString(s map[string]interface{}, doc string, at string) {
+	n := utf8.RuneCountInString(doc)
+
+	if min, ok := schemaNumber(s, "minLength"); ok && float64(n) < min {
+		v.fail(at, "expected at least %v characters, got %d", min, n)
+	}
+
+	if max, ok := schemaNumber(s, "maxLength"); ok && float64(n) > max {
+		v.fail(at, "expected at most %v characters, got %d", max, n)
+	}
+
+	if p, ok := s["pattern"].(string); ok {
+		re, err := v.schema.pattern(p)
+		if err != nil {
+			v.fail(at, "invalid pattern: %s", p)
+		} else if !re.MatchString(doc) {
+			v.fail(at, "value does not match pattern: %s", p)
+		}
+	}
+}
+
+func (v *schemaValidator) checkNumber(s map[string]interface{}, doc json.Number, at string) {
+	f, err := doc.Float64()
+	if err != nil {
+		v.fail(at, "invalid number: %s", doc)
+		return
+	}
+
+	if min, ok := schemaNumber(s, "minimum"); ok && f < min {
+		v.fail(at, "value %v is less than minimum %v", f, min)
+	}
+
+	if max, ok := schemaNumber(s, "maximum"); ok && f > max {
+		v.fail(at, "value %v is greater than maximum %v", f, max)
+	}
+
+	if min, ok := schemaNumber(s, "exclusiveMinimum"); ok && f <= min {
+		v.fail(at, "value %v must be greater than %v", f, min)
+	}
+
+	if max, ok := schemaNumber(s, "exclusiveMaximum"); ok && f >= max {
+		v.fail(at, "value %v must be less than %v", f, max)
+	}
+}
+
+func (v *schemaValidator) checkType(t, doc interface{}) bool {
+	switch tt := t.(type) {
+	case string:
+		return typeMatches(tt, doc)
+	case []interface{}:
+		for _, n := range tt {
+			if name, ok := n.(string); ok && typeMatches(name, doc) {
+				return true
+			}
+		}
+		return false
+	}
+
+	return true
+}
+
+// resolve follows local json pointers such as #/definitions/user
+func (s *Schema) resolve(ref string) (interface{}, error) {
+	if !strings.HasPrefix(ref, "#") {
+		return nil, fmt.Errorf("unsupported remote $ref: %s", ref)
+	}
+
+	node := s.root
+
+	for _, part := range strings.Split(strings.TrimPrefix(ref[1:], "/"), "/") {
+		if part == "" {
+			continue
+		}
+
+		part = strings.NewReplacer("~1", "/", "~0", "~").Replace(part)
+
+		m, ok := node.(map[string]interface{})
+		if !ok {
+			return nil, fmt.Errorf("unresolvable $ref: %s", ref)
+		}
+
+		if node, ok = m[part]; !ok {
+			return nil, fmt.Errorf("unresolvable $ref: %s", ref)
+		}
+	}
+
+	return node, nil
+}
+
+func typeMatches(name string, doc interface{}) bool {
+	switch name {
+	case "integer":
+		n, ok := doc.(json.Number)
+		if !ok {
+			return false
+		}
+		f, err := n.Float64()
+		return err == nil && f == math.Trunc(f)
+	case "number":
+		_, ok := doc.(json.Number)
+		return ok
+	default:
+		return jsonType(doc) == name
+	}
+}
+
+func typeNames(t interface{}) string {
+	if list, ok := t.([]interface{}); ok {
+		names := make([]string, len(list))
+		for i, n := range list {
+			names[i] = fmt.Sprint(n)
+		}
+		return strings.Join(names, " or ")
+	}
+
+	return fmt.Sprint(t)
+}
+
+func jsonType(doc interface{}) string {
+	switch doc.(type) {
+	case nil:
+		return "null"
+	case bool:
+		return "boolean"
+	case json.Number, float64:
+		return "number"
+	case string:
+		return "string"
+	case []interface{}:
+		return "array"
+	case map[string]interface{}:
+		return "object"
+	}
+
+	return "unknown"
+}
+
+func jsonEqual(a, b interface{}) bool {
+	ja, err := json.Marshal(a)
+	if err != nil {
+		return false
+	}
+
+	// schema values decode as float64 while documents use json.Number
+	var na, nb interface{}
+
+	json.Unmarshal(ja, &na)
+
+	jb, err := json.Marshal(b)
+	if err != nil {
+		return false
+	}
+
+	json.Unmarshal(jb, &nb)
+
+	ja, _ = json.Marshal(na)
+	jb, _ = json.Marshal(nb)
+
+	return bytes.Equal(ja, jb)
+}
+
+func schemaNumber(s map[string]interface{}, key string) (float64, bool) {
+	f, ok := s[key].(float64)
+	return f, ok
+}
+
+// schemaFor picks the per-request schema or the first Schemas pattern
+// matching the request path relative to the endpoint
+func (c *Client) schemaFor(res *http.Response, opts RequestOptions) *Schema {
+	if opts.Schema != nil {
+		return opts.Schema
+	}
+
+	if len(c.Schemas) == 0 || res.Request == nil || res.Request.URL == nil {
+		return nil
+	}
+
+	p := c.relativePath(res.Request.URL)
+
+	patterns := make([]string, 0, len(c.Schemas))
+	for k := range c.Schemas {
+		patterns = append(patterns, k)
+	}
+	sort.Strings(patterns)
+
+	for _, pattern := range patterns {
+		method, pp := "", pattern
+
+		if i := strings.IndexByte(pattern, ' '); i > 0 {
+			method, pp = pattern[:i], strings.TrimSpace(pattern[i+1:])
+		}
+
+		if method != "" && !strings.EqualFold(method, res.Request.Method) {
+			continue
+		}
+
+		if ok, _ := path.Match(pp, p); ok {
+			return c.Schemas[pattern]
+		}
+	}
+
+	return nil
+}
+
+func (c *Client) validateResponse(res *http.Response, opts RequestOptions) error {
+	s := c.schemaFor(res, opts)
+	if s == nil {
+		return nil
+	}
+
+	data, err := ioutil.ReadAll(res.Body)
+	res.Body.Close()
+	if err != nil {
+		return err
+	}
+
+	res.Body = ioutil.NopCloser(bytes.NewReader(data))
+
+	if len(bytes.TrimSpace(data)) == 0 {
+		return nil
+	}
+
+	return s.validate(data, c.StrictSchemas)
+}
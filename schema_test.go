@@ -0,0 +1,87 @@
+package stdsdk_test
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/liamdawson/stdsdk"
+)
+
+func TestSchemaValidate(t *testing.T) {
+	tests := []struct {
+		name   string
+		schema string
+		doc    string
+		err    string
+	}{
+		{"type", `{"type":"string"}`, `"a"`, ""},
+		{"type mismatch", `{"type":"string"}`, `1`, "expected string, got number"},
+		{"integer", `{"type":"integer"}`, `1.5`, "expected integer, got number"},
+		{"enum", `{"enum":["a","b"]}`, `"c"`, "value not in enum"},
+		{"const number", `{"const":1}`, `1`, ""},
+		{"required", `{"type":"object","required":["id"]}`, `{}`, "missing required property: id"},
+		{"property", `{"properties":{"id":{"type":"integer"}}}`, `{"id":"x"}`, "$.id: expected integer"},
+		{"additional", `{"properties":{},"additionalProperties":false}`, `{"x":1}`, "unexpected property: x"},
+		{"items", `{"items":{"type":"string"}}`, `["a",2]`, "$[1]: expected string"},
+		{"length", `{"minLength":2}`, `"a"`, "expected at least 2 characters"},
+		{"pattern", `{"pattern":"^a+$"}`, `"ab"`, "does not match pattern"},
+		{"range", `{"maximum":3}`, `4`, "greater than maximum"},
+		{"anyOf", `{"anyOf":[{"type":"string"},{"type":"null"}]}`, `null`, ""},
+		{"oneOf", `{"oneOf":[{"type":"number"},{"type":"integer"}]}`, `1`, "matches 2 schemas in oneOf"},
+		{"not", `{"not":{"type":"null"}}`, `null`, "value matches schema in not"},
+		{"ref", `{"definitions":{"id":{"type":"integer"}},"$ref":"#/definitions/id"}`, `"x"`, "expected integer"},
+		{"recursive ref", `{"definitions":{"node":{"properties":{"next":{"$ref":"#/definitions/node"}},"required":["v"]}},"$ref":"#/definitions/node"}`, `{"v":1,"next":{"v":2,"next":{}}}`, "$.next.next: missing required property: v"},
+		{"circular ref", `{"definitions":{"a":{"$ref":"#/definitions/b"},"b":{"$ref":"#/definitions/a"}},"$ref":"#/definitions/a"}`, `1`, "circular $ref"},
+		{"circular anyOf", `{"definitions":{"a":{"anyOf":[{"$ref":"#/definitions/a"}]}},"$ref":"#/definitions/a"}`, `1`, "anyOf"},
+		{"remote ref", `{"$ref":"other.json#/a"}`, `1`, "unsupported remote $ref"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, err := stdsdk.ParseSchema([]byte(tt.schema))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			err = s.Validate([]byte(tt.doc))
+
+			switch {
+			case tt.err == "" && err != nil:
+				t.Errorf("unexpected error: %v", err)
+			case tt.err != "" && err == nil:
+				t.Errorf("expected error containing %q", tt.err)
+			case tt.err != "" && !strings.Contains(err.Error(), tt.err):
+				t.Errorf("error = %v, want %q", err, tt.err)
+			}
+		})
+	}
+}
+
+func TestSchemaSkipsStreams(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"id":"x"}`))
+	}))
+	defer ts.Close()
+
+	c, err := stdsdk.New(ts.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	opts := stdsdk.RequestOptions{Schema: stdsdk.MustParseSchema([]byte(`{"properties":{"id":{"type":"integer"}}}`))}
+
+	var buf bytes.Buffer
+
+	if err := c.Get("/", opts, &buf); err != nil {
+		t.Fatalf("writer: %v", err)
+	}
+
+	var out map[string]interface{}
+
+	if err := c.Get("/", opts, &out); err == nil {
+		t.Error("map: expected schema error")
+	}
+}
@@ -0,0 +1,239 @@
+package stdsdk
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	SignatureHeader        = "X-Signature"
+	defaultSignatureWindow = 5 * time.Minute
+)
+
+// HMACAuth signs the timestamp, a random nonce, the method, the request uri
+// and a hash of the body with a shared secret, SignatureVerifier checks the
+// result on the server
+type HMACAuth struct {
+	Clock  Clock
+	KeyID  string
+	Secret []byte
+}
+
+func (a HMACAuth) credentials() []string {
+	return []string{string(a.Secret)}
+}
+
+func (a HMACAuth) Apply(ctx context.Context, req *http.Request) error {
+	clock := contextClock(ctx, a.Clock)
+
+	body, err := signingBody(req)
+	if err != nil {
+		return err
+	}
+
+	nb := make([]byte, 16)
+
+	if _, err := rand.Read(nb); err != nil {
+		return err
+	}
+
+	ts := strconv.FormatInt(clock.Now().Unix(), 10)
+	nonce := hex.EncodeToString(nb)
+
+	parts := []string{"t=" + ts, "n=" + nonce}
+
+	if a.KeyID != "" {
+		parts = append(parts, "k="+a.KeyID)
+	}
+
+	parts = append(parts, "v1="+signature(a.Secret, ts, nonce, req.Method, req.URL.RequestURI(), body))
+
+	req.Header.Set(SignatureHeader, strings.Join(parts, ","))
+
+	return nil
+}
+
+// NonceStore remembers nonces until they expire, Seen records nonce and
+// reports whether it had already been recorded
+type NonceStore interface {
+	Seen(ctx context.Context, nonce string, expires time.Time) (bool, error)
+}
+
+// MemoryNonceStore is a NonceStore for single process servers
+type MemoryNonceStore struct {
+	Clock Clock
+
+	lock   sync.Mutex
+	nonces map[string]time.Time
+}
+
+func (s *MemoryNonceStore) Seen(ctx context.Context, nonce string, expires time.Time) (bool, error) {
+	now := contextClock(ctx, s.Clock).Now()
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	if s.nonces == nil {
+		s.nonces = map[string]time.Time{}
+	}
+
+	for n, exp := range s.nonces {
+		if now.After(exp) {
+			delete(s.nonces, n)
+		}
+	}
+
+	if _, ok := s.nonces[nonce]; ok {
+		return true, nil
+	}
+
+	s.nonces[nonce] = expires
+
+	return false, nil
+}
+
+// SignatureVerifier checks requests signed with HMACAuth, a request is
+// rejected if its timestamp is outside Window or its nonce was seen before
+type SignatureVerifier struct {
+	Clock  Clock
+	Nonces NonceStore
+	Secret func(keyID string) ([]byte, error)
+	Window time.Duration
+}
+
+type SignatureError struct {
+	Reason string
+}
+
+func (e *SignatureError) Error() string {
+	return fmt.Sprintf("invalid signature: %s", e.Reason)
+}
+
+// Verify checks req and leaves its body readable
+func (v *SignatureVerifier) Verify(req *http.Request) error {
+	sig, err := parseSignature(req.Header.Get(SignatureHeader))
+	if err != nil {
+		return err
+	}
+
+	clock := orClock(v.Clock, nil)
+
+	window := v.Window
+	if window <= 0 {
+		window = defaultSignatureWindow
+	}
+
+	ts, err := strconv.ParseInt(sig["t"], 10, 64)
+	if err != nil {
+		return &SignatureError{Reason: "invalid timestamp"}
+	}
+
+	at := time.Unix(ts, 0)
+	now := clock.Now()
+
+	if at.Before(now.Add(-window)) || at.After(now.Add(window)) {
+		return &SignatureError{Reason: "timestamp outside window"}
+	}
+
+	secret, err := v.Secret(sig["k"])
+	if err != nil {
+		return err
+	}
+
+	body, err := signingBody(req)
+	if err != nil {
+		return err
+	}
+
+	expected := signature(secret, sig["t"], sig["n"], req.Method, req.URL.RequestURI(), body)
+
+	if !hmac.Equal([]byte(expected), []byte(sig["v1"])) {
+		return &SignatureError{Reason: "signature mismatch"}
+	}
+
+	// checked last so unauthenticated requests cannot fill the store
+	if v.Nonces != nil {
+		seen, err := v.Nonces.Seen(withClock(req.Context(), clock), sig["n"], at.Add(window))
+		if err != nil {
+			return err
+		}
+		if seen {
+			return &SignatureError{Reason: "replayed nonce"}
+		}
+	}
+
+	return nil
+}
+
+// Middleware rejects requests that fail Verify with a 401
+func (v *SignatureVerifier) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if err := v.Verify(r); err != nil {
+			http.Error(w, err.Error(), http.StatusUnauthorized)
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+func parseSignature(h string) (map[string]string, error) {
+	if h == "" {
+		return nil, &SignatureError{Reason: "missing " + SignatureHeader}
+	}
+
+	sig := map[string]string{}
+
+	for _, part := range strings.Split(h, ",") {
+		if kv := strings.SplitN(strings.TrimSpace(part), "=", 2); len(kv) == 2 {
+			sig[kv[0]] = kv[1]
+		}
+	}
+
+	if sig["t"] == "" || sig["n"] == "" || sig["v1"] == "" {
+		return nil, &SignatureError{Reason: "malformed " + SignatureHeader}
+	}
+
+	return sig, nil
+}
+
+func signature(secret []byte, ts, nonce, method, uri string, body []byte) string {
+	sum := sha256.Sum256(body)
+
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte(strings.Join([]string{ts, nonce, method, uri, hex.EncodeToString(sum[:])}, "\n")))
+
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// signingBody reads the body and replaces it so it can still be sent or handled
+func signingBody(req *http.Request) ([]byte, error) {
+	if req.Body == nil || req.Body == http.NoBody {
+		return nil, nil
+	}
+
+	data, err := ioutil.ReadAll(req.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	req.Body.Close()
+	req.Body = ioutil.NopCloser(bytes.NewReader(data))
+	req.GetBody = func() (io.ReadCloser, error) {
+		return ioutil.NopCloser(bytes.NewReader(data)), nil
+	}
+
+	return data, nil
+}
@@ -0,0 +1,75 @@
+package stdsdk
+
+import (
+	"context"
+	"net/http"
+	"sync/atomic"
+	"time"
+)
+
+type clockSkew struct {
+	nanos    int64
+	observed int32
+}
+
+// observe estimates skew against the midpoint of the round trip, the Date
+// header only has second resolution so smaller offsets are noise
+func (s *clockSkew) observe(res *http.Response, sent, received time.Time) {
+	if s == nil || res == nil {
+		return
+	}
+
+	d, err := http.ParseTime(res.Header.Get("Date"))
+	if err != nil {
+		return
+	}
+
+	local := sent.Add(received.Sub(sent) / 2)
+
+	skew := d.Sub(local)
+
+	if skew > -time.Second && skew < time.Second {
+		skew = 0
+	}
+
+	atomic.StoreInt64(&s.nanos, int64(skew))
+	atomic.StoreInt32(&s.observed, 1)
+}
+
+func (s *clockSkew) get() time.Duration {
+	if s == nil {
+		return 0
+	}
+
+	return time.Duration(atomic.LoadInt64(&s.nanos))
+}
+
+// ClockSkew reports how far the server clock is ahead of the local one as
+// of the last response carrying a Date header
+func (c *Client) ClockSkew() (time.Duration, bool) {
+	if c.skew == nil || atomic.LoadInt32(&c.skew.observed) == 0 {
+		return 0, false
+	}
+
+	return c.skew.get(), true
+}
+
+// ServerClock follows the server's time. Auth such as JWTAuth and HMACAuth
+// signs with it unless given a Clock of its own, so timestamps stay valid
+// when the local clock drifts
+func (c *Client) ServerClock() Clock {
+	return skewedClock{base: c.clock(), skew: c.skew}
+}
+
+type skewedClock struct {
+	base Clock
+	skew *clockSkew
+}
+
+func (c skewedClock) Now() time.Time {
+	return c.base.Now().Add(c.skew.get())
+}
+
+func (c skewedClock) Sleep(ctx context.Context, d time.Duration) error {
+	return c.base.Sleep(ctx, d)
+}
@@ -0,0 +1,134 @@
+package stdsdk
+
+import (
+	"crypto/tls"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptrace"
+	"os"
+	"sync"
+	"time"
+)
+
+// SlowLog reports requests whose response headers take longer than
+// Threshold, Func is called if set otherwise a line is written to Writer
+type SlowLog struct {
+	Func      func(SlowRequest)
+	Threshold time.Duration
+	Writer    io.Writer
+}
+
+// SlowRequest breaks down where the time went, phases that did not happen
+// such as dns on a reused connection are zero
+type SlowRequest struct {
+	Connect  time.Duration
+	DNS      time.Duration
+	Duration time.Duration
+	Error    error
+	Method   string
+	Reused   bool
+	Status   int
+	TLS      time.Duration
+	URL      string
+	Wait     time.Duration
+}
+
+type slowTrace struct {
+	lock sync.Mutex
+
+	connectStart time.Time
+	dnsStart     time.Time
+	tlsStart     time.Time
+	wrote        time.Time
+
+	sr SlowRequest
+}
+
+func (s *SlowLog) trace(req *http.Request) (*http.Request, *slowTrace) {
+	st := &slowTrace{}
+
+	ct := &httptrace.ClientTrace{
+		DNSStart: func(httptrace.DNSStartInfo) {
+			st.lock.Lock()
+			st.dnsStart = time.Now()
+			st.lock.Unlock()
+		},
+		DNSDone: func(httptrace.DNSDoneInfo) {
+			st.lock.Lock()
+			st.sr.DNS = time.Since(st.dnsStart)
+			st.lock.Unlock()
+		},
+		ConnectStart: func(string, string) {
+			st.lock.Lock()
+			st.connectStart = time.Now()
+			st.lock.Unlock()
+		},
+		ConnectDone: func(string, string, error) {
+			st.lock.Lock()
+			st.sr.Connect = time.Since(st.connectStart)
+			st.lock.Unlock()
+		},
+		TLSHandshakeStart: func() {
+			st.lock.Lock()
+			st.tlsStart = time.Now()
+			st.lock.Unlock()
+		},
+		TLSHandshakeDone: func(tls.ConnectionState, error) {
+			st.lock.Lock()
+			st.sr.TLS = time.Since(st.tlsStart)
+			st.lock.Unlock()
+		},
+		GotConn: func(info httptrace.GotConnInfo) {
+			st.lock.Lock()
+			st.sr.Reused = info.Reused
+			st.lock.Unlock()
+		},
+		WroteRequest: func(httptrace.WroteRequestInfo) {
+			st.lock.Lock()
+			st.wrote = time.Now()
+			st.lock.Unlock()
+		},
+		GotFirstResponseByte: func() {
+			st.lock.Lock()
+			if !st.wrote.IsZero() {
+				st.sr.Wait = time.Since(st.wrote)
+			}
+			st.lock.Unlock()
+		},
+	}
+
+	return req.WithContext(httptrace.WithClientTrace(req.Context(), ct)), st
+}
+
+func (s *SlowLog) observe(st *slowTrace, req *http.Request, res *http.Response, err error, d time.Duration) {
+	if d < s.Threshold {
+		return
+	}
+
+	st.lock.Lock()
+	sr := st.sr
+	st.lock.Unlock()
+
+	sr.Duration = d
+	sr.Error = err
+	sr.Method = req.Method
+	sr.URL = redactRequestURL(req)
+
+	if res != nil {
+		sr.Status = res.StatusCode
+	}
+
+	if s.Func != nil {
+		s.Func(sr)
+		return
+	}
+
+	w := s.Writer
+	if w == nil {
+		w = os.Stderr
+	}
+
+	fmt.Fprintf(w, "slow request: %s %s status=%d duration=%s dns=%s connect=%s tls=%s wait=%s reused=%t\n",
+		sr.Method, sr.URL, sr.Status, sr.Duration, sr.DNS, sr.Connect, sr.TLS, sr.Wait, sr.Reused)
+}
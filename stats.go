@@ -0,0 +1,145 @@
+package stdsdk
+
+import (
+	"context"
+	"io"
+	"net"
+	"net/http"
+	"sync"
+	"sync/atomic"
+)
+
+type Stats struct {
+	BytesReceived int64
+	BytesSent     int64
+	IdleConns     int64
+	InFlight      int64
+	OpenConns     int64
+	Requests      int64
+	Retries       int64
+}
+
+type clientStats struct {
+	bytesReceived int64
+	bytesSent     int64
+	inFlight      int64
+	requests      int64
+	retries       int64
+}
+
+// Stats reports counters shared by all clients derived from the same New
+// call, idle connections are estimated as open connections not carrying
+// an in-flight request
+func (c *Client) Stats() Stats {
+	var s Stats
+
+	if c.stats != nil {
+		s.BytesReceived = atomic.LoadInt64(&c.stats.bytesReceived)
+		s.BytesSent = atomic.LoadInt64(&c.stats.bytesSent)
+		s.InFlight = atomic.LoadInt64(&c.stats.inFlight)
+		s.Requests = atomic.LoadInt64(&c.stats.requests)
+		s.Retries = atomic.LoadInt64(&c.stats.retries)
+	}
+
+	if ct, ok := connTrackers.Load(c.httpClient().Transport); ok {
+		s.OpenConns = atomic.LoadInt64(&ct.(*connTracker).open)
+	}
+
+	if s.IdleConns = s.OpenConns - s.InFlight; s.IdleConns < 0 {
+		s.IdleConns = 0
+	}
+
+	return s
+}
+
+func (s *clientStats) retry() {
+	if s != nil {
+		atomic.AddInt64(&s.retries, 1)
+	}
+}
+
+func (s *clientStats) begin(req *http.Request) {
+	if s == nil {
+		return
+	}
+
+	atomic.AddInt64(&s.requests, 1)
+	atomic.AddInt64(&s.inFlight, 1)
+
+	// wrapping NoBody would make the transport send a chunked empty body
+	if req.Body != nil && req.Body != http.NoBody {
+		req.Body = &countingReader{ReadCloser: req.Body, n: &s.bytesSent}
+	}
+}
+
+// end counts the request as in flight until its response body is closed
+func (s *clientStats) end(res *http.Response) {
+	if s == nil {
+		return
+	}
+
+	if res == nil || res.Body == nil {
+		atomic.AddInt64(&s.inFlight, -1)
+		return
+	}
+
+	res.Body = &countingReader{ReadCloser: res.Body, n: &s.bytesReceived, inFlight: &s.inFlight}
+}
+
+type countingReader struct {
+	io.ReadCloser
+	inFlight *int64
+	n        *int64
+	once     sync.Once
+}
+
+func (r *countingReader) Read(p []byte) (int, error) {
+	n, err := r.ReadCloser.Read(p)
+	atomic.AddInt64(r.n, int64(n))
+	return n, err
+}
+
+func (r *countingReader) Close() error {
+	if r.inFlight != nil {
+		r.once.Do(func() { atomic.AddInt64(r.inFlight, -1) })
+	}
+
+	return r.ReadCloser.Close()
+}
+
+var connTrackers sync.Map
+
+type connTracker struct {
+	open int64
+}
+
+// trackConns wraps the transport dialer to count open connections
+func trackConns(t *http.Transport) {
+	ct := &connTracker{}
+
+	dial := t.DialContext
+
+	t.DialContext = func(ctx context.Context, network, addr string) (net.Conn, error) {
+		conn, err := dial(ctx, network, addr)
+		if err != nil {
+			return nil, err
+		}
+
+		atomic.AddInt64(&ct.open, 1)
+
+		return &trackedConn{Conn: conn, tracker: ct}, nil
+	}
+
+	connTrackers.Store(t, ct)
+}
+
+type trackedConn struct {
+	net.Conn
+	once    sync.Once
+	tracker *connTracker
+}
+
+func (c *trackedConn) Close() error {
+	c.once.Do(func() { atomic.AddInt64(&c.tracker.open, -1) })
+	return c.Conn.Close()
+}
@@ -0,0 +1,34 @@
+package stdsdk_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/liamdawson/stdsdk"
+)
+
+func TestStatsInFlightAfterErrorResponse(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(500)
+		w.Write([]byte(`{"error":"boom"}`))
+	}))
+	defer ts.Close()
+
+	c, err := stdsdk.New(ts.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for i := 0; i < 3; i++ {
+		if err := c.Get("/", stdsdk.RequestOptions{}, nil); err == nil {
+			t.Fatal("expected error response")
+		}
+	}
+
+	s := c.Stats()
+
+	if s.InFlight != 0 {
+		t.Errorf("in flight = %d, want 0", s.InFlight)
+	}
+}
@@ -0,0 +1,73 @@
+package stdsdktest
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/liamdawson/stdsdk"
+)
+
+type Clock struct {
+	lock    sync.Mutex
+	now     time.Time
+	waiters []clockWaiter
+}
+
+type clockWaiter struct {
+	at time.Time
+	ch chan struct{}
+}
+
+var _ stdsdk.Clock = &Clock{}
+
+func NewClock(now time.Time) *Clock {
+	return &Clock{now: now}
+}
+
+func (c *Clock) Now() time.Time {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	return c.now
+}
+
+func (c *Clock) Sleep(ctx context.Context, d time.Duration) error {
+	c.lock.Lock()
+
+	if d <= 0 {
+		c.lock.Unlock()
+		return nil
+	}
+
+	w := clockWaiter{at: c.now.Add(d), ch: make(chan struct{})}
+	c.waiters = append(c.waiters, w)
+
+	c.lock.Unlock()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-w.ch:
+		return nil
+	}
+}
+
+func (c *Clock) Advance(d time.Duration) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	c.now = c.now.Add(d)
+
+	waiters := c.waiters[:0]
+
+	for _, w := range c.waiters {
+		if c.now.Before(w.at) {
+			waiters = append(waiters, w)
+			continue
+		}
+		close(w.ch)
+	}
+
+	c.waiters = waiters
+}
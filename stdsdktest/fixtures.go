@@ -0,0 +1,209 @@
+package stdsdktest
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Fixture describes a canned response, Body is written as is when it is a
+// string and encoded as json otherwise. BodyFile is relative to the fixture
+type Fixture struct {
+	Body     interface{}            `json:"body" yaml:"body"`
+	BodyFile string                 `json:"bodyFile" yaml:"bodyFile"`
+	Headers  map[string]string      `json:"headers" yaml:"headers"`
+	Method   string                 `json:"method" yaml:"method"`
+	Path     string                 `json:"path" yaml:"path"`
+	Query    map[string]interface{} `json:"query" yaml:"query"`
+	Status   int                    `json:"status" yaml:"status"`
+
+	body []byte
+}
+
+func NewFixtureServer(dir string) (*Server, error) {
+	s := NewServer()
+
+	if err := s.LoadFixtures(dir); err != nil {
+		s.Close()
+		return nil, err
+	}
+
+	return s, nil
+}
+
+// LoadFixtures serves every .json, .yaml and .yml file in dir, each holding
+// one fixture or a list of them. Fixtures for the same route are matched in
+// file order by their Query
+func (s *Server) LoadFixtures(dir string) error {
+	files, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return err
+	}
+
+	names := []string{}
+
+	for _, f := range files {
+		switch strings.ToLower(filepath.Ext(f.Name())) {
+		case ".json", ".yaml", ".yml":
+			if !f.IsDir() {
+				names = append(names, f.Name())
+			}
+		}
+	}
+
+	sort.Strings(names)
+
+	routes := map[string][]*Fixture{}
+	order := []string{}
+
+	for _, name := range names {
+		fs, err := readFixtures(filepath.Join(dir, name))
+		if err != nil {
+			return fmt.Errorf("%s: %w", name, err)
+		}
+
+		for _, f := range fs {
+			key := routeKey(f.Method, f.Path)
+			if _, ok := routes[key]; !ok {
+				order = append(order, key)
+			}
+			routes[key] = append(routes[key], f)
+		}
+	}
+
+	for _, key := range order {
+		fs := routes[key]
+		s.Handle(fs[0].Method, fs[0].Path, fixtureHandler(fs))
+	}
+
+	return nil
+}
+
+func readFixtures(path string) ([]*Fixture, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var raw interface{}
+
+	if ext := strings.ToLower(filepath.Ext(path)); ext == ".json" {
+		err = json.Unmarshal(data, &raw)
+	} else {
+		err = yaml.Unmarshal(data, &raw)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	// round trip through json so yaml and json fixtures decode the same way
+	data, err = json.Marshal(raw)
+	if err != nil {
+		return nil, err
+	}
+
+	fs := []*Fixture{}
+
+	if _, ok := raw.([]interface{}); ok {
+		err = json.Unmarshal(data, &fs)
+	} else {
+		f := &Fixture{}
+		err = json.Unmarshal(data, f)
+		fs = append(fs, f)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	for _, f := range fs {
+		if err := f.prepare(filepath.Dir(path)); err != nil {
+			return nil, err
+		}
+	}
+
+	return fs, nil
+}
+
+func (f *Fixture) prepare(dir string) error {
+	if f.Path == "" {
+		return fmt.Errorf("fixture missing path")
+	}
+
+	if f.Method == "" {
+		f.Method = "GET"
+	}
+
+	f.Method = strings.ToUpper(f.Method)
+
+	if f.Status == 0 {
+		f.Status = http.StatusOK
+	}
+
+	if f.Headers == nil {
+		f.Headers = map[string]string{}
+	}
+
+	switch {
+	case f.BodyFile != "":
+		data, err := ioutil.ReadFile(filepath.Join(dir, f.BodyFile))
+		if err != nil {
+			return err
+		}
+		f.body = data
+	case f.Body == nil:
+	default:
+		if s, ok := f.Body.(string); ok {
+			f.body = []byte(s)
+			break
+		}
+		data, err := json.Marshal(f.Body)
+		if err != nil {
+			return err
+		}
+		f.body = data
+		if _, ok := f.Headers["Content-Type"]; !ok {
+			f.Headers["Content-Type"] = "application/json"
+		}
+	}
+
+	return nil
+}
+
+func (f *Fixture) matches(r *http.Request) bool {
+	q := r.URL.Query()
+
+	for k, v := range f.Query {
+		if q.Get(k) != fmt.Sprint(v) {
+			return false
+		}
+	}
+
+	return true
+}
+
+func fixtureHandler(fs []*Fixture) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		for _, f := range fs {
+			if !f.matches(r) {
+				continue
+			}
+
+			for k, v := range f.Headers {
+				w.Header().Set(k, v)
+			}
+
+			w.WriteHeader(f.Status)
+			w.Write(f.body)
+
+			return
+		}
+
+		http.Error(w, fmt.Sprintf("no fixture matches %s %s", r.Method, r.URL), http.StatusNotFound)
+	}
+}
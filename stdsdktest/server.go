@@ -0,0 +1,112 @@
+package stdsdktest
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+
+	"github.com/gorilla/websocket"
+	"github.com/liamdawson/stdsdk"
+)
+
+type Server struct {
+	*httptest.Server
+
+	lock   sync.Mutex
+	routes map[string]http.HandlerFunc
+}
+
+func NewServer() *Server {
+	s := &Server{routes: map[string]http.HandlerFunc{}}
+
+	s.Server = httptest.NewServer(http.HandlerFunc(s.serve))
+
+	return s
+}
+
+func NewTLSServer() *Server {
+	s := &Server{routes: map[string]http.HandlerFunc{}}
+
+	s.Server = httptest.NewTLSServer(http.HandlerFunc(s.serve))
+
+	return s
+}
+
+func (s *Server) Handle(method, path string, fn http.HandlerFunc) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	s.routes[routeKey(method, path)] = fn
+}
+
+func (s *Server) HandleJSON(method, path string, status int, body string) {
+	s.Handle(method, path, func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(status)
+		fmt.Fprint(w, body)
+	})
+}
+
+func (s *Server) HandleEcho(path string) {
+	s.Handle("GET", path, WebsocketEcho)
+}
+
+func (s *Server) Client() *stdsdk.Client {
+	c, err := stdsdk.New(s.URL)
+	if err != nil {
+		panic(err)
+	}
+
+	// trusts the test certificate instead of skipping verification
+	if s.TLS != nil {
+		c.Transport = s.Server.Client().Transport
+	}
+
+	return c
+}
+
+func (s *Server) serve(w http.ResponseWriter, r *http.Request) {
+	s.lock.Lock()
+	fn, ok := s.routes[routeKey(r.Method, r.URL.Path)]
+	s.lock.Unlock()
+
+	if !ok {
+		http.Error(w, fmt.Sprintf("no route for %s %s", r.Method, r.URL.Path), http.StatusNotFound)
+		return
+	}
+
+	fn(w, r)
+}
+
+var upgrader = websocket.Upgrader{
+	CheckOrigin: func(r *http.Request) bool { return true },
+}
+
+func WebsocketEcho(w http.ResponseWriter, r *http.Request) {
+	ws, err := upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		return
+	}
+
+	defer ws.Close()
+
+	for {
+		code, data, err := ws.ReadMessage()
+		if err != nil {
+			return
+		}
+
+		if err := ws.WriteMessage(code, data); err != nil {
+			return
+		}
+
+		if code == websocket.BinaryMessage {
+			return
+		}
+	}
+}
+
+func routeKey(method, path string) string {
+	return fmt.Sprintf("%s %s", method, path)
+}
@@ -0,0 +1,39 @@
+package stdsdk
+
+import (
+	"fmt"
+	"log/slog"
+)
+
+// String describes the client without credentials so it is safe to log
+func (c *Client) String() string {
+	return fmt.Sprintf("stdsdk.Client{Endpoint: %s}", c.safeEndpoint())
+}
+
+func (c *Client) GoString() string {
+	return c.String()
+}
+
+func (c *Client) LogValue() slog.Value {
+	attrs := []slog.Attr{slog.String("endpoint", c.safeEndpoint())}
+
+	if c.Auth != nil {
+		attrs = append(attrs, slog.String("auth", fmt.Sprintf("%T", c.Auth)))
+	}
+
+	return slog.GroupValue(attrs...)
+}
+
+// safeEndpoint drops userinfo and the query, which can carry api keys
+func (c *Client) safeEndpoint() string {
+	if c == nil || c.Endpoint == nil {
+		return ""
+	}
+
+	u := *c.Endpoint
+	u.User = nil
+	u.RawQuery = ""
+	u.Fragment = ""
+
+	return u.String()
+}
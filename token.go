@@ -0,0 +1,112 @@
+package stdsdk
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+type Token struct {
+	Value  string
+	Expiry time.Time
+}
+
+type TokenSource interface {
+	Token(ctx context.Context) (*Token, error)
+}
+
+type TokenSourceFunc func(ctx context.Context) (*Token, error)
+
+func (fn TokenSourceFunc) Token(ctx context.Context) (*Token, error) {
+	return fn(ctx)
+}
+
+type CachedTokenSource struct {
+	Clock         Clock
+	RefreshBefore time.Duration
+	Source        TokenSource
+
+	lock     sync.Mutex
+	token    *Token
+	inflight *tokenCall
+}
+
+type tokenCall struct {
+	done  chan struct{}
+	token *Token
+	err   error
+}
+
+func NewCachedTokenSource(src TokenSource, refreshBefore time.Duration) *CachedTokenSource {
+	return &CachedTokenSource{RefreshBefore: refreshBefore, Source: src}
+}
+
+// Token returns the cached token, one within RefreshBefore of expiry is still
+// returned while a replacement is fetched in the background
+func (s *CachedTokenSource) Token(ctx context.Context) (*Token, error) {
+	now := localClock(ctx, s.Clock).Now()
+
+	s.lock.Lock()
+
+	if s.usable(now) {
+		t := s.token
+
+		if s.stale(now) && s.inflight == nil {
+			s.inflight = &tokenCall{done: make(chan struct{})}
+			go s.fetch(ctx, s.inflight)
+		}
+
+		s.lock.Unlock()
+		return t, nil
+	}
+
+	call := s.inflight
+
+	if call == nil {
+		call = &tokenCall{done: make(chan struct{})}
+		s.inflight = call
+		go s.fetch(ctx, call)
+	}
+
+	s.lock.Unlock()
+
+	select {
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	case <-call.done:
+		return call.token, call.err
+	}
+}
+
+func (s *CachedTokenSource) Invalidate() {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	s.token = nil
+}
+
+func (s *CachedTokenSource) fetch(ctx context.Context, call *tokenCall) {
+	// detach so one caller giving up does not fail everyone waiting
+	t, err := s.Source.Token(context.WithoutCancel(ctx))
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	call.token, call.err = t, err
+
+	if err == nil {
+		s.token = t
+	}
+
+	s.inflight = nil
+
+	close(call.done)
+}
+
+func (s *CachedTokenSource) usable(now time.Time) bool {
+	return s.token != nil && (s.token.Expiry.IsZero() || now.Before(s.token.Expiry))
+}
+
+func (s *CachedTokenSource) stale(now time.Time) bool {
+	return !s.token.Expiry.IsZero() && !now.Add(s.RefreshBefore).Before(s.token.Expiry)
+}
@@ -0,0 +1,110 @@
+package stdsdk_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/liamdawson/stdsdk"
+)
+
+type fixedClock struct {
+	now time.Time
+}
+
+func (c *fixedClock) Now() time.Time {
+	return c.now
+}
+
+func (c *fixedClock) Sleep(ctx context.Context, d time.Duration) error {
+	c.now = c.now.Add(d)
+	return nil
+}
+
+func TestCachedTokenSourceBackgroundRefresh(t *testing.T) {
+	clock := &fixedClock{now: time.Unix(1000, 0)}
+	release := make(chan struct{})
+	fetched := make(chan string, 2)
+
+	n := 0
+
+	src := stdsdk.TokenSourceFunc(func(ctx context.Context) (*stdsdk.Token, error) {
+		n++
+		if n > 1 {
+			<-release
+		}
+
+		v := string(rune('a' + n - 1))
+		fetched <- v
+
+		return &stdsdk.Token{Value: v, Expiry: clock.Now().Add(time.Minute)}, nil
+	})
+
+	s := &stdsdk.CachedTokenSource{Clock: clock, RefreshBefore: 10 * time.Second, Source: src}
+
+	tok, err := s.Token(context.Background())
+	if err != nil || tok.Value != "a" {
+		t.Fatalf("first token = %v, %v", tok, err)
+	}
+	<-fetched
+
+	// inside the refresh window the current token comes back without waiting
+	clock.now = clock.now.Add(55 * time.Second)
+
+	tok, err = s.Token(context.Background())
+	if err != nil || tok.Value != "a" {
+		t.Fatalf("stale token = %v, %v", tok, err)
+	}
+
+	close(release)
+
+	if v := <-fetched; v != "b" {
+		t.Fatalf("refreshed token = %s", v)
+	}
+}
+
+func TestTokenAuthNilToken(t *testing.T) {
+	a := stdsdk.TokenAuth{Source: stdsdk.TokenSourceFunc(func(ctx context.Context) (*stdsdk.Token, error) {
+		return nil, nil
+	})}
+
+	req, _ := http.NewRequest("GET", "http://example.com", nil)
+
+	if err := a.Apply(context.Background(), req); err == nil {
+		t.Error("expected error for nil token")
+	}
+}
+
+func TestCachedTokenSourceIgnoresServerSkew(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Date", time.Now().Add(time.Hour).UTC().Format(http.TimeFormat))
+	}))
+	defer ts.Close()
+
+	c, err := stdsdk.New(ts.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	fetches := 0
+
+	src := stdsdk.TokenSourceFunc(func(ctx context.Context) (*stdsdk.Token, error) {
+		fetches++
+		return &stdsdk.Token{Value: "t", Expiry: time.Now().Add(time.Minute)}, nil
+	})
+
+	c.Auth = stdsdk.TokenAuth{Source: stdsdk.NewCachedTokenSource(src, 0)}
+
+	// the first response sets the skew, the cached token is still good locally
+	for i := 0; i < 3; i++ {
+		if err := c.Get("/", stdsdk.RequestOptions{}, nil); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if fetches != 1 {
+		t.Errorf("fetches = %d, want 1", fetches)
+	}
+}
@@ -0,0 +1,57 @@
+package stdsdk
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strconv"
+)
+
+// Tracer adapts the client to a tracing system, StartSpan returns a context
+// carrying the span and EndSpan is called with that context once response
+// headers arrive or the request fails
+type Tracer interface {
+	StartSpan(ctx context.Context, name string, attrs map[string]string) context.Context
+	EndSpan(ctx context.Context, attrs map[string]string, err error)
+}
+
+// TraceInjector is implemented by tracers that propagate context to the
+// server through request headers
+type TraceInjector interface {
+	Inject(ctx context.Context, h http.Header)
+}
+
+func (c *Client) startSpan(req *http.Request) *http.Request {
+	path := req.URL.Path
+
+	if t, ok := req.Context().Value(templateKey{}).(string); ok {
+		path = t
+	}
+
+	ctx := c.Tracer.StartSpan(req.Context(), fmt.Sprintf("%s %s", req.Method, path), map[string]string{
+		"http.method": req.Method,
+		"http.route":  path,
+		"http.url":    redactRequestURL(req),
+		"net.peer":    req.URL.Host,
+	})
+
+	if ti, ok := c.Tracer.(TraceInjector); ok {
+		ti.Inject(ctx, req.Header)
+	}
+
+	return req.WithContext(ctx)
+}
+
+func (c *Client) endSpan(req *http.Request, o *outcome, err error) {
+	attrs := map[string]string{}
+
+	if o.status > 0 {
+		attrs["http.status_code"] = strconv.Itoa(o.status)
+	}
+
+	if o.requestID != "" {
+		attrs["http.request_id"] = o.requestID
+	}
+
+	c.Tracer.EndSpan(req.Context(), attrs, err)
+}
@@ -0,0 +1,41 @@
+package stdsdk
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+)
+
+type TrailerError struct {
+	Header  string
+	Message string
+}
+
+func (e *TrailerError) Error() string {
+	return fmt.Sprintf("stream failed: %s", e.Message)
+}
+
+// trailerBody turns a non-empty error trailer into the read error at eof
+type trailerBody struct {
+	io.ReadCloser
+	header string
+	res    *http.Response
+}
+
+func (b *trailerBody) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+
+	if err == io.EOF {
+		if msg := b.res.Trailer.Get(b.header); msg != "" {
+			return n, &TrailerError{Header: b.header, Message: msg}
+		}
+	}
+
+	return n, err
+}
+
+func trailerErrors(res *http.Response, opts RequestOptions) {
+	if opts.TrailerError != "" {
+		res.Body = &trailerBody{ReadCloser: res.Body, header: opts.TrailerError, res: res}
+	}
+}
@@ -0,0 +1,533 @@
+package stdsdk
+
+import (
+	"context"
+	"crypto/fips140"
+	"crypto/tls"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"syscall"
+	"time"
+)
+
+type IPFamily int
+
+const (
+	IPAny IPFamily = iota
+	IPv4Only
+	IPv6Only
+	PreferIPv4
+	PreferIPv6
+)
+
+type TLSProfile int
+
+const (
+	TLSDefault TLSProfile = iota
+	// TLSFIPS requires the go fips 140 module to be enabled, dials fail otherwise
+	TLSFIPS
+)
+
+type TransportOptions struct {
+	Clock                 Clock
+	ConnectTimeout        time.Duration
+	Control               func(network, address string, c syscall.RawConn) error
+	DisableKeepAlives     bool
+	DisableSessionCache   bool
+	FailoverCooldown      time.Duration
+	FailoverThreshold     int
+	FallbackDelay         time.Duration
+	HappyEyeballs         bool
+	Hosts                 map[string]string
+	IPFamily              IPFamily
+	IdleConnTimeout       time.Duration
+	InsecureSkipVerify    bool
+	Interface             string
+	KeepAlive             time.Duration
+	LocalAddr             string
+	MaxConnsPerHost       int
+	MaxIdleConns          int
+	MaxIdleConnsPerHost   int
+	ReadIdleTimeout       time.Duration
+	ResponseHeaderTimeout time.Duration
+	Revocation            RevocationChecker
+	ServerName            string
+	SessionCacheSize      int
+	TLSHandshakeTimeout   time.Duration
+	TLSProfile            TLSProfile
+}
+
+func NewTransport(opts TransportOptions) *http.Transport {
+	d := &net.Dialer{
+		Timeout:   30 * time.Second,
+		KeepAlive: 10 * time.Second,
+	}
+
+	if opts.ConnectTimeout > 0 {
+		d.Timeout = opts.ConnectTimeout
+	}
+
+	// negative disables tcp keep-alive probes
+	if opts.KeepAlive != 0 {
+		d.KeepAlive = opts.KeepAlive
+	}
+
+	if opts.Control != nil {
+		d.Control = opts.Control
+	}
+
+	if opts.FallbackDelay != 0 {
+		d.FallbackDelay = opts.FallbackDelay
+	}
+
+	var dial dialFunc = d.DialContext
+
+	if opts.LocalAddr != "" || opts.Interface != "" {
+		dial = func(ctx context.Context, network, addr string) (net.Conn, error) {
+			ip, err := localIP(opts.LocalAddr, opts.Interface)
+			if err != nil {
+				return nil, err
+			}
+
+			dd := *d
+			dd.LocalAddr = &net.TCPAddr{IP: ip}
+
+			return dd.DialContext(ctx, network, addr)
+		}
+	}
+
+	if opts.IPFamily != IPAny || opts.HappyEyeballs {
+		dial = familyDialer(dial, opts)
+	}
+
+	if opts.FailoverThreshold > 0 {
+		dial = failoverDialer(dial, opts)
+	}
+
+	if len(opts.Hosts) > 0 {
+		dial = hostDialer(dial, opts.Hosts)
+	}
+
+	t := &http.Transport{
+		DialContext:           dial,
+		DisableKeepAlives:     opts.DisableKeepAlives,
+		MaxIdleConns:          100,
+		IdleConnTimeout:       90 * time.Second,
+		TLSHandshakeTimeout:   10 * time.Second,
+		ExpectContinueTimeout: 1 * time.Second,
+		// skipping verification is an explicit opt-in, the settings below
+		// that depend on a verified chain turn it back on
+		TLSClientConfig: &tls.Config{
+			InsecureSkipVerify: opts.InsecureSkipVerify,
+		},
+	}
+
+	if opts.IdleConnTimeout > 0 {
+		t.IdleConnTimeout = opts.IdleConnTimeout
+	}
+
+	if opts.ResponseHeaderTimeout > 0 {
+		t.ResponseHeaderTimeout = opts.ResponseHeaderTimeout
+	}
+
+	if opts.TLSHandshakeTimeout > 0 {
+		t.TLSHandshakeTimeout = opts.TLSHandshakeTimeout
+	}
+
+	// zero size uses the tls package default capacity
+	if opts.DisableSessionCache {
+		t.TLSClientConfig.SessionTicketsDisabled = true
+	} else {
+		t.TLSClientConfig.ClientSessionCache = tls.NewLRUClientSessionCache(opts.SessionCacheSize)
+	}
+
+	// the profile only narrows tls, the crypto underneath must be the
+	// validated module so without it every dial fails
+	if opts.TLSProfile == TLSFIPS {
+		fipsProfile(t.TLSClientConfig)
+
+		if !fips140.Enabled() {
+			t.DialContext = func(ctx context.Context, network, addr string) (net.Conn, error) {
+				return nil, errFIPSDisabled
+			}
+		}
+	}
+
+	// revocation checks only mean something against a verified chain
+	if opts.Revocation != nil {
+		t.TLSClientConfig.InsecureSkipVerify = false
+		t.TLSClientConfig.VerifyConnection = opts.Revocation.CheckRevocation
+
+		// checkers without a clock of their own follow the transport's
+		if rc, ok := opts.Revocation.(clockedRevocation); ok && opts.Clock != nil {
+			t.TLSClientConfig.VerifyConnection = func(cs tls.ConnectionState) error {
+				return rc.checkRevocation(cs, opts.Clock)
+			}
+		}
+	}
+
+	// host overrides keep the original name for tls, which only protects
+	// anything when the certificate is checked against it
+	if len(opts.Hosts) > 0 {
+		t.TLSClientConfig.InsecureSkipVerify = false
+	}
+
+	// presenting a name only helps if the certificate is checked against it
+	if opts.ServerName != "" {
+		t.TLSClientConfig.InsecureSkipVerify = false
+		t.TLSClientConfig.ServerName = opts.ServerName
+	}
+
+	if opts.MaxConnsPerHost > 0 {
+		t.MaxConnsPerHost = opts.MaxConnsPerHost
+	}
+
+	if opts.MaxIdleConns > 0 {
+		t.MaxIdleConns = opts.MaxIdleConns
+	}
+
+	if opts.MaxIdleConnsPerHost > 0 {
+		t.MaxIdleConnsPerHost = opts.MaxIdleConnsPerHost
+	}
+
+	if opts.ReadIdleTimeout > 0 {
+		readIdleTimeouts.Store(t, opts.ReadIdleTimeout)
+	}
+
+	trackConns(t)
+	platformTransport(t)
+
+	return t
+}
+
+var errFIPSDisabled = fmt.Errorf("tls profile fips requires fips 140 mode, run with GODEBUG=fips140=on or build with GOFIPS140")
+
+// restricts tls to fips 140 approved versions, key exchanges and suites
+func fipsProfile(cfg *tls.Config) {
+	cfg.InsecureSkipVerify = false
+	cfg.MinVersion = tls.VersionTLS12
+	cfg.CurvePreferences = []tls.CurveID{tls.CurveP256, tls.CurveP384}
+	cfg.CipherSuites = []uint16{
+		tls.TLS_ECDHE_ECDSA_WITH_AES_128_GCM_SHA256,
+		tls.TLS_ECDHE_ECDSA_WITH_AES_256_GCM_SHA384,
+		tls.TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256,
+		tls.TLS_ECDHE_RSA_WITH_AES_256_GCM_SHA384,
+	}
+}
+
+func localIP(addr, iface string) (net.IP, error) {
+	if addr != "" {
+		ip := net.ParseIP(addr)
+		if ip == nil {
+			return nil, fmt.Errorf("invalid local address: %s", addr)
+		}
+		return ip, nil
+	}
+
+	i, err := net.InterfaceByName(iface)
+	if err != nil {
+		return nil, err
+	}
+
+	addrs, err := i.Addrs()
+	if err != nil {
+		return nil, err
+	}
+
+	var fallback net.IP
+
+	for _, a := range addrs {
+		if n, ok := a.(*net.IPNet); ok {
+			if n.IP.To4() != nil {
+				return n.IP, nil
+			}
+			if fallback == nil {
+				fallback = n.IP
+			}
+		}
+	}
+
+	if fallback == nil {
+		return nil, fmt.Errorf("no addresses on interface: %s", iface)
+	}
+
+	return fallback, nil
+}
+
+type dialFunc func(ctx context.Context, network, addr string) (net.Conn, error)
+
+func familyDialer(dial dialFunc, opts TransportOptions) dialFunc {
+	family := opts.IPFamily
+
+	delay := opts.FallbackDelay
+	if delay <= 0 {
+		delay = 250 * time.Millisecond
+	}
+
+	return func(ctx context.Context, network, addr string) (net.Conn, error) {
+		switch family {
+		case IPv4Only:
+			network = "tcp4"
+		case IPv6Only:
+			network = "tcp6"
+		}
+
+		host, port, err := net.SplitHostPort(addr)
+		if err != nil {
+			return nil, err
+		}
+
+		if net.ParseIP(host) != nil {
+			return dial(ctx, network, addr)
+		}
+
+		ips, err := net.DefaultResolver.LookupIPAddr(ctx, host)
+		if err != nil {
+			return nil, err
+		}
+
+		addrs := []string{}
+
+		for _, ip := range sortFamilies(ips, family, opts.HappyEyeballs) {
+			addrs = append(addrs, net.JoinHostPort(ip.String(), port))
+		}
+
+		if len(addrs) == 0 {
+			return nil, fmt.Errorf("no addresses for host: %s", host)
+		}
+
+		if opts.HappyEyeballs {
+			return raceDial(ctx, dial, network, addrs, delay)
+		}
+
+		var last error
+
+		for _, a := range addrs {
+			conn, err := dial(ctx, network, a)
+			if err == nil {
+				return conn, nil
+			}
+			last = err
+		}
+
+		return nil, last
+	}
+}
+
+// sortFamilies orders addresses by family preference, filtering for the
+// forced families and interleaving families when racing per rfc 8305
+func sortFamilies(ips []net.IPAddr, family IPFamily, interleave bool) []net.IP {
+	var v4, v6 []net.IP
+
+	for _, ip := range ips {
+		if ip.IP.To4() != nil {
+			v4 = append(v4, ip.IP)
+		} else {
+			v6 = append(v6, ip.IP)
+		}
+	}
+
+	switch family {
+	case IPv4Only:
+		return v4
+	case IPv6Only:
+		return v6
+	}
+
+	first, second := v6, v4
+
+	if family == PreferIPv4 {
+		first, second = v4, v6
+	}
+
+	if !interleave {
+		return append(first, second...)
+	}
+
+	sorted := []net.IP{}
+
+	for len(first) > 0 || len(second) > 0 {
+		if len(first) > 0 {
+			sorted = append(sorted, first[0])
+			first = first[1:]
+		}
+		if len(second) > 0 {
+			sorted = append(sorted, second[0])
+			second = second[1:]
+		}
+	}
+
+	return sorted
+}
+
+// raceDial starts a connection attempt every delay, or as soon as the
+// previous attempt fails, returning the first to succeed
+func raceDial(ctx context.Context, dial dialFunc, network string, addrs []string, delay time.Duration) (net.Conn, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	type result struct {
+		conn net.Conn
+		err  error
+	}
+
+	results := make(chan result, len(addrs))
+
+	pending := 0
+	next := 0
+
+	start := func() {
+		a := addrs[next]
+		next++
+		pending++
+		go func() {
+			conn, err := dial(ctx, network, a)
+			results <- result{conn, err}
+		}()
+	}
+
+	start()
+
+	var last error
+
+	for pending > 0 {
+		var timer <-chan time.Time
+
+		t := time.NewTimer(delay)
+
+		if next < len(addrs) {
+			timer = t.C
+		}
+
+		select {
+		case r := <-results:
+			t.Stop()
+			pending--
+
+			if r.err == nil {
+				// close any late winners once we return
+				go func(n int) {
+					for i := 0; i < n; i++ {
+						if lr := <-results; lr.conn != nil {
+							lr.conn.Close()
+						}
+					}
+				}(pending)
+
+				return r.conn, nil
+			}
+
+			last = r.err
+
+			if next < len(addrs) {
+				start()
+			}
+		case <-timer:
+			start()
+		}
+	}
+
+	return nil, last
+}
+
+// hostDialer connects to a fixed address for mapped hosts, tls still uses
+// the request host for SNI and verification
+func hostDialer(dial dialFunc, hosts map[string]string) dialFunc {
+	m := map[string]string{}
+
+	for k, v := range hosts {
+		m[strings.ToLower(k)] = v
+	}
+
+	// a transport without DialContext dials with a zero net.Dialer
+	if dial == nil {
+		dial = (&net.Dialer{}).DialContext
+	}
+
+	return func(ctx context.Context, network, addr string) (net.Conn, error) {
+		host, port, err := net.SplitHostPort(addr)
+		if err != nil {
+			return nil, err
+		}
+
+		if ip, ok := m[strings.ToLower(host)]; ok {
+			addr = net.JoinHostPort(ip, port)
+		}
+
+		return dial(ctx, network, addr)
+	}
+}
+
+// WithHostOverride returns a client dialing ip for host while verifying the
+// certificate against host, it has no effect when Transport is not an
+// *http.Transport
+func (c *Client) WithHostOverride(host, ip string) *Client {
+	d := *c
+
+	t, ok := c.httpClient().Transport.(*http.Transport)
+	if !ok {
+		return &d
+	}
+
+	nt := cloneTransport(t)
+	nt.DialContext = hostDialer(t.DialContext, map[string]string{host: ip})
+
+	if nt.TLSClientConfig == nil {
+		nt.TLSClientConfig = &tls.Config{}
+	}
+
+	nt.TLSClientConfig.InsecureSkipVerify = false
+
+	d.Transport = nt
+
+	return &d
+}
+
+// cloneTransport copies t for further customization, connection counts
+// keep accruing to the original for Stats
+func cloneTransport(t *http.Transport) *http.Transport {
+	nt := t.Clone()
+
+	if ct, ok := connTrackers.Load(t); ok {
+		connTrackers.Store(nt, ct)
+	}
+
+	if d, ok := readIdleTimeouts.Load(t); ok {
+		readIdleTimeouts.Store(nt, d)
+	}
+
+	return nt
+}
+
+// readIdleTimeouts holds the ReadIdleTimeout of each transport, it is
+// enforced on response bodies since a deadline on the connection would also
+// expire idle keep-alive connections
+var readIdleTimeouts sync.Map
+
+// idleBody fails body reads that make no progress within timeout, unlike a
+// total timeout this lets long streams run as long as data keeps arriving
+type idleBody struct {
+	io.ReadCloser
+	expired int32
+	timeout time.Duration
+}
+
+func (b *idleBody) Read(p []byte) (int, error) {
+	t := time.AfterFunc(b.timeout, func() {
+		atomic.StoreInt32(&b.expired, 1)
+		b.ReadCloser.Close()
+	})
+
+	n, err := b.ReadCloser.Read(p)
+
+	if !t.Stop() && atomic.LoadInt32(&b.expired) == 1 {
+		return n, fmt.Errorf("no data received for %s", b.timeout)
+	}
+
+	return n, err
+}
@@ -0,0 +1,10 @@
+//go:build js
+
+package stdsdk
+
+import "net/http"
+
+// net/http only uses the browser fetch api when no dialer is configured
+func platformTransport(t *http.Transport) {
+	t.DialContext = nil
+}
@@ -0,0 +1,7 @@
+//go:build !js
+
+package stdsdk
+
+import "net/http"
+
+func platformTransport(t *http.Transport) {}
@@ -0,0 +1,168 @@
+package stdsdk_test
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/liamdawson/stdsdk"
+)
+
+func TestReadIdleTimeout(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("a"))
+		w.(http.Flusher).Flush()
+
+		if r.URL.Path == "/stall" {
+			time.Sleep(200 * time.Millisecond)
+		}
+	}))
+	defer ts.Close()
+
+	c, err := stdsdk.New(ts.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	conns := 0
+
+	c.Transport = stdsdk.NewTransport(stdsdk.TransportOptions{ReadIdleTimeout: 50 * time.Millisecond})
+	c.Transport.(*http.Transport).DialContext = countDials(c.Transport.(*http.Transport), &conns)
+
+	var s string
+
+	if err := c.Get("/", stdsdk.RequestOptions{}, &s); err != nil {
+		t.Fatal(err)
+	}
+
+	// an idle keep-alive connection outlives the timeout and is reused
+	time.Sleep(100 * time.Millisecond)
+
+	if err := c.Get("/", stdsdk.RequestOptions{}, &s); err != nil {
+		t.Fatal(err)
+	}
+
+	if conns != 1 {
+		t.Errorf("dialed %d connections, want 1", conns)
+	}
+
+	res, err := c.GetStream("/stall", stdsdk.RequestOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer res.Body.Close()
+
+	if _, err := ioutil.ReadAll(res.Body); err == nil || !strings.Contains(err.Error(), "no data received") {
+		t.Errorf("stalled body: err = %v", err)
+	}
+}
+
+func countDials(t *http.Transport, n *int) func(ctx context.Context, network, addr string) (net.Conn, error) {
+	dial := t.DialContext
+
+	return func(ctx context.Context, network, addr string) (net.Conn, error) {
+		*n++
+		return dial(ctx, network, addr)
+	}
+}
+
+func TestWithHostOverridePlainTransport(t *testing.T) {
+	ts := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(r.Host))
+	}))
+	defer ts.Close()
+
+	_, port, err := net.SplitHostPort(ts.Listener.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	c, err := stdsdk.New("https://example.com:" + port)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// a plain transport has no DialContext, the test certificate is issued
+	// for example.com so verification succeeds only through the override
+	c.Transport = &http.Transport{TLSClientConfig: ts.Client().Transport.(*http.Transport).TLSClientConfig.Clone()}
+
+	var s string
+
+	if err := c.WithHostOverride("example.com", "127.0.0.1").Get("/", stdsdk.RequestOptions{}, &s); err != nil {
+		t.Fatal(err)
+	}
+
+	if want := "example.com:" + port; s != want {
+		t.Errorf("host = %q, want %q", s, want)
+	}
+
+	// the certificate is still checked against the original name
+	o, err := stdsdk.New("https://other.test:" + port)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	o.Transport = &http.Transport{TLSClientConfig: &tls.Config{InsecureSkipVerify: true}}
+
+	if err := o.WithHostOverride("other.test", "127.0.0.1").Get("/", stdsdk.RequestOptions{}, nil); err == nil {
+		t.Error("expected certificate verification error")
+	}
+}
+
+func TestNewTransportVerifiesByDefault(t *testing.T) {
+	ts := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer ts.Close()
+
+	c, err := stdsdk.New(ts.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	c.Transport = stdsdk.NewTransport(stdsdk.TransportOptions{})
+
+	if err := c.Get("/", stdsdk.RequestOptions{}, nil); err == nil {
+		t.Error("expected certificate verification error")
+	}
+
+	c.Transport = stdsdk.NewTransport(stdsdk.TransportOptions{InsecureSkipVerify: true})
+
+	if err := c.Get("/", stdsdk.RequestOptions{}, nil); err != nil {
+		t.Errorf("skip verify: %v", err)
+	}
+}
+
+func TestTransportHosts(t *testing.T) {
+	ts := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer ts.Close()
+
+	_, port, err := net.SplitHostPort(ts.Listener.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	roots := x509.NewCertPool()
+	roots.AddCert(ts.Certificate())
+
+	for host, ok := range map[string]bool{"example.com": true, "other.test": false} {
+		c, err := stdsdk.New("https://" + host + ":" + port)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		// even an explicit skip is overridden once hosts are mapped
+		tr := stdsdk.NewTransport(stdsdk.TransportOptions{Hosts: map[string]string{host: "127.0.0.1"}, InsecureSkipVerify: true})
+		tr.TLSClientConfig.RootCAs = roots
+		c.Transport = tr
+
+		if err := c.Get("/", stdsdk.RequestOptions{}, nil); (err == nil) != ok {
+			t.Errorf("%s: err = %v, want success %t", host, err, ok)
+		}
+	}
+}
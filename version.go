@@ -0,0 +1,56 @@
+package stdsdk
+
+import (
+	"fmt"
+	"runtime"
+	"runtime/debug"
+	"strings"
+)
+
+const modulePath = "github.com/liamdawson/stdsdk"
+
+// Version is the module version recorded in the build info, it can also be
+// set with -ldflags "-X github.com/liamdawson/stdsdk.Version=v1.2.3"
+var Version = "dev"
+
+func init() {
+	if Version != "dev" {
+		return
+	}
+
+	if v := moduleVersion(); v != "" && v != "(devel)" {
+		Version = v
+	}
+}
+
+func moduleVersion() string {
+	bi, ok := debug.ReadBuildInfo()
+	if !ok {
+		return ""
+	}
+
+	if bi.Main.Path == modulePath {
+		return bi.Main.Version
+	}
+
+	for _, d := range bi.Deps {
+		if d.Path == modulePath {
+			if d.Replace != nil {
+				return d.Replace.Version
+			}
+			return d.Version
+		}
+	}
+
+	return ""
+}
+
+func (c *Client) userAgent() string {
+	ua := fmt.Sprintf("stdsdk/%s go/%s", Version, strings.TrimPrefix(runtime.Version(), "go"))
+
+	if c.UserAgent != "" {
+		ua += " " + c.UserAgent
+	}
+
+	return ua
+}
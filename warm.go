@@ -0,0 +1,61 @@
+package stdsdk
+
+import (
+	"context"
+	"io"
+	"io/ioutil"
+	"sync"
+)
+
+// Warm opens up to n connections to the endpoint by issuing concurrent HEAD
+// requests, the transport only keeps MaxIdleConnsPerHost of them idle. The
+// requests go to the endpoint path with auth, Limiter and Bulkheads applied,
+// any status counts since the connection is open either way
+func (c *Client) Warm(ctx context.Context, n int) error {
+	cc := c.WithContext(context.WithValue(ctx, anyStatusKey{}, true))
+	cc.OpenAPI = nil
+
+	var wg sync.WaitGroup
+
+	errs := make(chan error, n)
+
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+
+		go func() {
+			defer wg.Done()
+
+			req, err := cc.Request("HEAD", "", RequestOptions{})
+			if err != nil {
+				errs <- err
+				return
+			}
+
+			res, err := cc.HandleRequest(req)
+			if err != nil {
+				errs <- err
+				return
+			}
+
+			io.Copy(ioutil.Discard, res.Body)
+			res.Body.Close()
+		}()
+	}
+
+	wg.Wait()
+	close(errs)
+
+	var err error
+	failed := 0
+
+	for e := range errs {
+		err = e
+		failed++
+	}
+
+	if n > 0 && failed == n {
+		return err
+	}
+
+	return nil
+}
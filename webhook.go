@@ -0,0 +1,40 @@
+package stdsdk
+
+import (
+	"io/ioutil"
+	"net/http"
+	"time"
+)
+
+// VerifyWebhook checks an inbound webhook signed with HMACAuth and returns
+// its body, a zero tolerance uses the default signature window
+func VerifyWebhook(req *http.Request, secret []byte, tolerance time.Duration) ([]byte, error) {
+	v := &SignatureVerifier{
+		Secret: func(string) ([]byte, error) { return secret, nil },
+		Window: tolerance,
+	}
+
+	if err := v.Verify(req); err != nil {
+		return nil, err
+	}
+
+	if req.Body == nil {
+		return nil, nil
+	}
+
+	return ioutil.ReadAll(req.Body)
+}
+
+// WebhookHandler calls fn with the verified body, deliveries with a bad
+// signature get a 401 and never reach fn
+func WebhookHandler(secret []byte, tolerance time.Duration, fn func(w http.ResponseWriter, r *http.Request, body []byte)) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, err := VerifyWebhook(r, secret, tolerance)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusUnauthorized)
+			return
+		}
+
+		fn(w, r, body)
+	})
+}
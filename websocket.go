@@ -0,0 +1,40 @@
+//go:build !js
+
+package stdsdk
+
+import (
+	"crypto/tls"
+	"net/http"
+
+	"github.com/gorilla/websocket"
+)
+
+func (c *Client) dialWebsocket(u string, h http.Header) (wsConn, error) {
+	ws, _, err := c.dialer().DialContext(c.ctx, u, h)
+	if err != nil {
+		return nil, err
+	}
+
+	return ws, nil
+}
+
+// dialer returns a private copy so per-client settings never touch
+// websocket.DefaultDialer
+func (c *Client) dialer() *websocket.Dialer {
+	d := *websocket.DefaultDialer
+
+	d.TLSClientConfig = &tls.Config{}
+
+	// a nil Transport means DefaultClient, whose tls settings apply here too
+	if t, ok := c.httpClient().Transport.(*http.Transport); ok {
+		d.NetDialContext = t.DialContext
+		if t.Proxy != nil {
+			d.Proxy = t.Proxy
+		}
+		if t.TLSClientConfig != nil {
+			d.TLSClientConfig = t.TLSClientConfig.Clone()
+		}
+	}
+
+	return &d
+}
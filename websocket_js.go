@@ -0,0 +1,180 @@
+//go:build js
+
+package stdsdk
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"net/http"
+	"sync"
+	"syscall/js"
+
+	"github.com/gorilla/websocket"
+)
+
+// browsers do not allow custom headers on websocket upgrades so h is
+// ignored and cookies for the origin are sent instead
+func (c *Client) dialWebsocket(u string, h http.Header) (wsConn, error) {
+	ws := js.Global().Get("WebSocket").New(u)
+	ws.Set("binaryType", "arraybuffer")
+
+	conn := &jsWebsocket{ws: ws, ready: make(chan struct{}, 1)}
+
+	opened := make(chan error, 1)
+
+	conn.listen("open", func(js.Value) {
+		select {
+		case opened <- nil:
+		default:
+		}
+	})
+
+	conn.listen("error", func(js.Value) {
+		select {
+		case opened <- fmt.Errorf("websocket error: %s", u):
+		default:
+		}
+	})
+
+	conn.listen("message", func(ev js.Value) {
+		data := ev.Get("data")
+
+		if data.Type() == js.TypeString {
+			conn.push(websocket.TextMessage, []byte(data.String()))
+			return
+		}
+
+		arr := js.Global().Get("Uint8Array").New(data)
+		buf := make([]byte, arr.Length())
+		js.CopyBytesToGo(buf, arr)
+
+		conn.push(websocket.BinaryMessage, buf)
+	})
+
+	conn.listen("close", func(js.Value) {
+		conn.lock.Lock()
+		conn.closed = true
+		conn.lock.Unlock()
+
+		conn.signal()
+
+		select {
+		case opened <- fmt.Errorf("websocket closed: %s", u):
+		default:
+		}
+	})
+
+	select {
+	case <-c.ctx.Done():
+		conn.Close()
+		return nil, c.ctx.Err()
+	case err := <-opened:
+		if err != nil {
+			conn.Close()
+			return nil, err
+		}
+	}
+
+	go func() {
+		<-c.ctx.Done()
+		conn.Close()
+	}()
+
+	return conn, nil
+}
+
+type jsMessage struct {
+	code int
+	data []byte
+}
+
+type jsWebsocket struct {
+	ws    js.Value
+	ready chan struct{}
+
+	lock   sync.Mutex
+	closed bool
+	funcs  []js.Func
+	queue  []jsMessage
+}
+
+func (c *jsWebsocket) Close() error {
+	c.ws.Call("close")
+
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	for _, fn := range c.funcs {
+		fn.Release()
+	}
+
+	c.funcs = nil
+
+	return nil
+}
+
+func (c *jsWebsocket) NextReader() (int, io.Reader, error) {
+	for {
+		c.lock.Lock()
+
+		if len(c.queue) > 0 {
+			m := c.queue[0]
+			c.queue = c.queue[1:]
+			c.lock.Unlock()
+			return m.code, bytes.NewReader(m.data), nil
+		}
+
+		closed := c.closed
+		c.lock.Unlock()
+
+		if closed {
+			return 0, nil, io.EOF
+		}
+
+		<-c.ready
+	}
+}
+
+func (c *jsWebsocket) WriteMessage(code int, data []byte) error {
+	if code == websocket.TextMessage {
+		c.ws.Call("send", string(data))
+		return nil
+	}
+
+	arr := js.Global().Get("Uint8Array").New(len(data))
+	js.CopyBytesToJS(arr, data)
+
+	c.ws.Call("send", arr.Get("buffer"))
+
+	return nil
+}
+
+// handlers run on the browser event loop so they must never block
+func (c *jsWebsocket) listen(event string, fn func(js.Value)) {
+	f := js.FuncOf(func(this js.Value, args []js.Value) interface{} {
+		fn(args[0])
+		return nil
+	})
+
+	c.lock.Lock()
+	c.funcs = append(c.funcs, f)
+	c.lock.Unlock()
+
+	c.ws.Call("addEventListener", event, f)
+}
+
+func (c *jsWebsocket) push(code int, data []byte) {
+	c.lock.Lock()
+	c.queue = append(c.queue, jsMessage{code: code, data: data})
+	c.lock.Unlock()
+
+	c.signal()
+}
+
+func (c *jsWebsocket) signal() {
+	select {
+	case c.ready <- struct{}{}:
+	default:
+	}
+}
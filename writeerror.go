@@ -0,0 +1,35 @@
+package stdsdk
+
+import (
+	"net/http"
+)
+
+// WriteError responds with the {"error": "..."} body responseError reads,
+// for servers that want clients to see err as the error message
+func WriteError(w http.ResponseWriter, status int, err error) {
+	writeErrorBody(w, "application/json", status, map[string]interface{}{"error": err.Error()})
+}
+
+// WriteProblem responds with an rfc 7807 problem+json body carrying err as
+// the detail, which clients of this package also understand
+func WriteProblem(w http.ResponseWriter, status int, err error) {
+	writeErrorBody(w, "application/problem+json", status, map[string]interface{}{
+		"detail": err.Error(),
+		"status": status,
+		"title":  http.StatusText(status),
+		"type":   "about:blank",
+	})
+}
+
+func writeErrorBody(w http.ResponseWriter, contentType string, status int, body map[string]interface{}) {
+	data, err := JSON.Marshal(body)
+	if err != nil {
+		http.Error(w, http.StatusText(status), status)
+		return
+	}
+
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.WriteHeader(status)
+	w.Write(append(data, '\n'))
+}
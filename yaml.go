@@ -0,0 +1,41 @@
+package stdsdk
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"gopkg.in/yaml.v3"
+)
+
+// yamlToJSON converts a yaml document to json, non string keys such as
+// response codes are formatted as strings
+func yamlToJSON(data []byte) ([]byte, error) {
+	var v interface{}
+
+	if err := yaml.Unmarshal(data, &v); err != nil {
+		return nil, err
+	}
+
+	return json.Marshal(jsonKeys(v))
+}
+
+func jsonKeys(v interface{}) interface{} {
+	switch t := v.(type) {
+	case map[string]interface{}:
+		for k, e := range t {
+			t[k] = jsonKeys(e)
+		}
+	case map[interface{}]interface{}:
+		m := make(map[string]interface{}, len(t))
+		for k, e := range t {
+			m[fmt.Sprint(k)] = jsonKeys(e)
+		}
+		return m
+	case []interface{}:
+		for i, e := range t {
+			t[i] = jsonKeys(e)
+		}
+	}
+
+	return v
+}